@@ -0,0 +1,69 @@
+package app
+
+import (
+	"errors"
+	"rs-go-server/io"
+	"rs-go-server/repo"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHashCost is the bcrypt cost of new password hashes.
+const PasswordHashCost = bcrypt.DefaultCost
+
+// authenticate checks the request's password against the account of its
+// username, creating the account first if it doesn't exist and the world
+// allows registering.  Without an account repository every login passes.
+func (w *World) authenticate(request *LoginRequest) LoginResponse {
+	if w.Accounts == nil {
+		return LOGIN_OK
+	}
+	// checked before encoding, NameToLong silently truncates long names
+	if !ValidUsername(request.Username) || len(request.Password) == 0 {
+		return LOGIN_INVALID_CREDENTIALS
+	}
+	name := io.NameToLong(request.Username)
+	account, err := w.Accounts.LoadAccount(name)
+	if err == repo.ErrAccountNotFound && w.Config.Login.AutoRegister {
+		var created bool
+		if account, created, err = w.register(name, request); created {
+			return LOGIN_OK
+		}
+	}
+	switch {
+	case err == repo.ErrAccountNotFound, errors.Is(err, bcrypt.ErrPasswordTooLong):
+		return LOGIN_INVALID_CREDENTIALS
+	case err != nil:
+		loginLog.Error("authentication failed", "username", request.Username, "address", request.Address, "error", err)
+		return LOGIN_COULD_NOT_COMPLETE
+	}
+	if bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), request.Password) != nil {
+		return LOGIN_INVALID_CREDENTIALS
+	}
+	return LOGIN_OK
+}
+
+// register creates an account with the request's credentials.  If another
+// login registered the name first, that account is returned instead so the
+// password can be checked against it.
+func (w *World) register(name int64, request *LoginRequest) (account *repo.AccountRecord, created bool, err error) {
+	hash, err := bcrypt.GenerateFromPassword(request.Password, PasswordHashCost)
+	if err != nil {
+		return nil, false, err
+	}
+	err = w.Accounts.CreateAccount(&repo.AccountRecord{
+		Name:         name,
+		Username:     request.Username,
+		PasswordHash: string(hash),
+		Created:      time.Now(),
+	})
+	if err == repo.ErrAccountExists {
+		account, err = w.Accounts.LoadAccount(name)
+		return account, false, err
+	} else if err != nil {
+		return nil, false, err
+	}
+	loginLog.Info("registered account", "username", request.Username, "address", request.Address)
+	return nil, true, nil
+}
@@ -0,0 +1,209 @@
+package app
+
+import "rs-go-server/io"
+
+const (
+	GENDER_MALE   = 0
+	GENDER_FEMALE = 1
+
+	// body kit indices, in the order of the character design packet
+	KIT_HEAD  = 0
+	KIT_BEARD = 1
+	KIT_CHEST = 2
+	KIT_ARMS  = 3
+	KIT_HANDS = 4
+	KIT_LEGS  = 5
+	KIT_FEET  = 6
+
+	// color indices, in the order of the character design packet
+	COLOR_HAIR  = 0
+	COLOR_CHEST = 1
+	COLOR_LEGS  = 2
+	COLOR_FEET  = 3
+	COLOR_SKIN  = 4
+
+	EQUIPMENT_HAT    = 0
+	EQUIPMENT_CAPE   = 1
+	EQUIPMENT_AMULET = 2
+	EQUIPMENT_WEAPON = 3
+	EQUIPMENT_CHEST  = 4
+	EQUIPMENT_SHIELD = 5
+	EQUIPMENT_LEGS   = 7
+	EQUIPMENT_HANDS  = 9
+	EQUIPMENT_FEET   = 10
+	EQUIPMENT_RING   = 12
+	EQUIPMENT_ARROWS = 13
+
+	EQUIPMENT_SIZE = 14
+
+	// a skulled player has the first headicon drawn above them
+	HEAD_ICON_SKULL = 0x1
+)
+
+var (
+	// valid kit ranges per gender, a range of -1 means the kit isn't used
+	kitRanges = [2][7][2]int{
+		GENDER_MALE:   {{0, 8}, {10, 17}, {18, 25}, {26, 31}, {33, 34}, {36, 40}, {42, 43}},
+		GENDER_FEMALE: {{45, 54}, {-1, -1}, {56, 60}, {61, 65}, {67, 68}, {70, 77}, {79, 80}},
+	}
+	colorRanges = [5]int{12, 16, 16, 6, 8}
+)
+
+type Appearance struct {
+	Gender int
+	Kits   [7]int
+	Colors [5]int
+}
+
+// MovementAnimations are the idle and walking animations the client plays by
+// itself, depending on what the player wields.
+type MovementAnimations struct {
+	Stand, StandTurn, Walk, Turn180, Turn90CW, Turn90CCW, Run int
+}
+
+var DefaultMovementAnimations = MovementAnimations{
+	Stand:     808,
+	StandTurn: 823,
+	Walk:      819,
+	Turn180:   820,
+	Turn90CW:  821,
+	Turn90CCW: 822,
+	Run:       824,
+}
+
+// field returns the animation with the given name in item definitions, or
+// nil.
+func (a *MovementAnimations) field(name string) *int {
+	switch name {
+	case "stand":
+		return &a.Stand
+	case "standTurn":
+		return &a.StandTurn
+	case "walk":
+		return &a.Walk
+	case "turn180":
+		return &a.Turn180
+	case "turn90CW":
+		return &a.Turn90CW
+	case "turn90CCW":
+		return &a.Turn90CCW
+	case "run":
+		return &a.Run
+	}
+	return nil
+}
+
+func DefaultAppearance() Appearance {
+	return Appearance{
+		Gender: GENDER_MALE,
+		Kits:   [7]int{0, 10, 18, 26, 33, 36, 42},
+		Colors: [5]int{7, 8, 9, 5, 0},
+	}
+}
+
+// Valid reports whether every kit and color exists for the gender.
+func (a *Appearance) Valid() bool {
+	if a.Gender != GENDER_MALE && a.Gender != GENDER_FEMALE {
+		return false
+	}
+	for i, kit := range a.Kits {
+		r := kitRanges[a.Gender][i]
+		if r[0] == -1 {
+			if kit != -1 {
+				return false
+			}
+		} else if kit < r[0] || kit > r[1] {
+			return false
+		}
+	}
+	for i, color := range a.Colors {
+		if color < 0 || color >= colorRanges[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetAppearance changes the player's looks and flags them for an update.
+func (p *Player) SetAppearance(appearance Appearance) {
+	p.Appearance = appearance
+	p.UpdateFlags |= UPDATE_APPEARANCE
+}
+
+func (p *Player) appendAppearance(buf *io.StreamBuffer) {
+	block := io.NewOutBuffer(128)
+	block.WriteByte(p.Appearance.Gender, io.STANDARD)
+	headIcon := p.HeadIcon
+	if p.Skulled {
+		headIcon |= HEAD_ICON_SKULL
+	}
+	block.WriteByte(headIcon, io.STANDARD)
+
+	// equipment, an item is sent as 0x200+id and a body kit as 0x100+id
+	p.appendEquipment(block, EQUIPMENT_HAT, -1)
+	p.appendEquipment(block, EQUIPMENT_CAPE, -1)
+	p.appendEquipment(block, EQUIPMENT_AMULET, -1)
+	p.appendEquipment(block, EQUIPMENT_WEAPON, -1)
+	p.appendEquipment(block, EQUIPMENT_CHEST, KIT_CHEST)
+	p.appendEquipment(block, EQUIPMENT_SHIELD, -1)
+	if e := p.equipped(EQUIPMENT_CHEST); e != nil && e.FullBody {
+		block.WriteByte(0, io.STANDARD)
+	} else {
+		p.appendKit(block, KIT_ARMS)
+	}
+	p.appendEquipment(block, EQUIPMENT_LEGS, KIT_LEGS)
+	if e := p.equipped(EQUIPMENT_HAT); e != nil && e.FullHelm {
+		block.WriteByte(0, io.STANDARD)
+	} else {
+		p.appendKit(block, KIT_HEAD)
+	}
+	p.appendEquipment(block, EQUIPMENT_HANDS, KIT_HANDS)
+	p.appendEquipment(block, EQUIPMENT_FEET, KIT_FEET)
+	if e := p.equipped(EQUIPMENT_HAT); e != nil && e.FullMask {
+		block.WriteByte(0, io.STANDARD)
+	} else {
+		p.appendKit(block, KIT_BEARD)
+	}
+
+	// colors
+	for _, color := range p.Appearance.Colors {
+		block.WriteByte(color, io.STANDARD)
+	}
+
+	// animations
+	animations := p.Animations
+	block.WriteShort(animations.Stand, io.STANDARD, io.BIG)
+	block.WriteShort(animations.StandTurn, io.STANDARD, io.BIG)
+	block.WriteShort(animations.Walk, io.STANDARD, io.BIG)
+	block.WriteShort(animations.Turn180, io.STANDARD, io.BIG)
+	block.WriteShort(animations.Turn90CW, io.STANDARD, io.BIG)
+	block.WriteShort(animations.Turn90CCW, io.STANDARD, io.BIG)
+	block.WriteShort(animations.Run, io.STANDARD, io.BIG)
+
+	block.WriteString(p.Username)
+	block.WriteByte(p.Skills.CombatLevel(), io.STANDARD)
+	block.WriteShort(0, io.STANDARD, io.BIG)
+
+	buf.WriteByte(block.Buffer.Position, io.C)
+	buf.WriteBytes(block.Buffer)
+}
+
+// appendEquipment writes the item worn in slot, falling back to the body kit
+// (or nothing when kit is -1) if the slot is empty.
+func (p *Player) appendEquipment(buf *io.StreamBuffer, slot, kit int) {
+	if item := p.Equipment.Get(slot); !item.Empty() {
+		buf.WriteShort(0x200+item.ID, io.STANDARD, io.BIG)
+	} else if kit != -1 {
+		p.appendKit(buf, kit)
+	} else {
+		buf.WriteByte(0, io.STANDARD)
+	}
+}
+
+func (p *Player) appendKit(buf *io.StreamBuffer, kit int) {
+	if id := p.Appearance.Kits[kit]; id >= 0 {
+		buf.WriteShort(0x100+id, io.STANDARD, io.BIG)
+	} else {
+		buf.WriteByte(0, io.STANDARD)
+	}
+}
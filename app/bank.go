@@ -0,0 +1,148 @@
+package app
+
+import "rs-go-server/io"
+
+const (
+	BANK_SIZE = 352
+
+	INTERFACE_BANK           = 5292
+	INTERFACE_BANK_SIDEBAR   = 5063 // the inventory shown next to the bank
+	INTERFACE_BANK_ITEMS     = 5382
+	INTERFACE_BANK_INVENTORY = 5064
+
+	CONFIG_BANK_NOTE   = 115
+	CONFIG_BANK_INSERT = 304
+
+	BUTTON_BANK_WITHDRAW_ITEM = 5386
+	BUTTON_BANK_WITHDRAW_NOTE = 5387
+	BUTTON_BANK_SWAP          = 8130
+	BUTTON_BANK_INSERT        = 8131
+)
+
+// amounts moved by the first four item options of the bank, the fifth asks
+// for one
+var bankOptionAmounts = [...]int{1: 1, 2: 5, 3: 10, 4: MaxStackAmount}
+
+func init() {
+	RegisterPacketHandler(130, 0, HandleCloseInterfacePacket)
+	RegisterPacketHandler(208, 4, HandleEnterAmountPacket)
+}
+
+func HandleCloseInterfacePacket(p *Player, packet *Packet) error {
+	p.interfaceClosed()
+	return nil
+}
+
+// HandleEnterAmountPacket is the answer to SendEnterAmount.
+func HandleEnterAmountPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	amount := int(int32(buf.ReadInt(io.STANDARD, io.BIG)))
+	callback := p.enterAmount
+	p.enterAmount = nil
+	if callback != nil && amount > 0 {
+		callback(amount)
+	}
+	return nil
+}
+
+// EnterAmount asks the player for a number and passes it to callback.
+func (p *Player) EnterAmount(callback func(amount int)) {
+	p.enterAmount = callback
+	p.SendEnterAmount()
+}
+
+func (p *Player) OpenBank() {
+	p.Bank.Refresh()
+	p.Inventory.AddListener(p.bankInventory)
+	p.Inventory.Refresh()
+	p.SendConfig(CONFIG_BANK_NOTE, boolToInt(p.BankNoteMode))
+	p.SendConfig(CONFIG_BANK_INSERT, boolToInt(p.BankInsertMode))
+	p.SendInventoryInterface(INTERFACE_BANK, INTERFACE_BANK_SIDEBAR)
+	p.openInterface = INTERFACE_BANK
+}
+
+// CloseInterfaces closes whatever interface the player has open.
+func (p *Player) CloseInterfaces() {
+	p.SendCloseInterfaces()
+	p.interfaceClosed()
+}
+
+// interfaceClosed forgets about the open interface once the client has
+// closed it.
+func (p *Player) interfaceClosed() {
+	if p.openInterface == INTERFACE_BANK {
+		p.Inventory.RemoveListener(p.bankInventory)
+	}
+	p.openInterface = 0
+	p.enterAmount = nil
+}
+
+func (p *Player) SetBankNoteMode(note bool) {
+	p.BankNoteMode = note
+	p.SendConfig(CONFIG_BANK_NOTE, boolToInt(note))
+}
+
+func (p *Player) SetBankInsertMode(insert bool) {
+	p.BankInsertMode = insert
+	p.SendConfig(CONFIG_BANK_INSERT, boolToInt(insert))
+}
+
+func (p *Player) bankItemOption(option int, transfer func(amount int)) {
+	if p.openInterface != INTERFACE_BANK {
+		return
+	}
+	if option == 5 {
+		p.EnterAmount(transfer)
+		return
+	}
+	transfer(bankOptionAmounts[option])
+}
+
+// Deposit moves up to amount of the item in an inventory slot into the bank.
+// Notes are banked as the item they stand for.
+func (p *Player) Deposit(slot, id, amount int) {
+	if p.openInterface != INTERFACE_BANK || p.Inventory.Get(slot).ID != id {
+		return
+	}
+	amount = min(amount, p.Inventory.Count(id))
+	added := p.Bank.Add(Item{UnnotedID(id), amount})
+	if added < amount {
+		p.SendMessage("You don't have enough space in your bank account.")
+	}
+	p.Inventory.Remove(Item{id, added})
+}
+
+// Withdraw moves up to amount of the item in a bank slot into the inventory,
+// as notes when note mode is on.
+func (p *Player) Withdraw(slot, id, amount int) {
+	if p.openInterface != INTERFACE_BANK || p.Bank.Get(slot).ID != id {
+		return
+	}
+	amount = min(amount, p.Bank.Get(slot).Amount)
+	withdrawn := id
+	if p.BankNoteMode {
+		if withdrawn = NotedID(id); withdrawn == id {
+			p.SendMessage("This item can't be withdrawn as a note.")
+		}
+	}
+	added := p.Inventory.Add(Item{withdrawn, amount})
+	if added < amount {
+		p.SendMessage("You don't have enough inventory space.")
+	}
+	p.Bank.RemoveFromSlot(slot, added)
+	p.Bank.Shift()
+}
+
+// MoveBankItem rearranges the bank by swapping or inserting, depending on the
+// player's setting.
+func (p *Player) MoveBankItem(from, to int) {
+	if p.openInterface != INTERFACE_BANK {
+		return
+	}
+	if p.BankInsertMode {
+		p.Bank.Insert(from, to)
+	} else {
+		p.Bank.Swap(from, to)
+	}
+	p.Bank.Shift()
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"fmt"
+	"rs-go-server/io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CommandError is reported back to the player that typed the command.
+type CommandError struct{ Message string }
+
+func (e CommandError) Error() string {
+	return e.Message
+}
+
+// CommandArgumentError is a CommandError caused by bad arguments, the
+// command's usage is shown along with it.
+type CommandArgumentError struct{ Message string }
+
+func (e CommandArgumentError) Error() string {
+	return e.Message
+}
+
+type CommandHandler func(p *Player, args *CommandArgs) error
+
+// Command is invoked by typing ::name args in the chat box.  Usage is shown
+// when the arguments can't be parsed.
+type Command struct {
+	Name    string
+	Aliases []string
+	Rights  int
+	Usage   string
+	Handler CommandHandler
+}
+
+var commands = make(map[string]*Command)
+
+func init() {
+	RegisterPacketHandler(103, VARIABLE_SIZE, HandleCommandPacket)
+}
+
+// RegisterCommand makes command available under its name and aliases, which
+// must not already be taken.
+func RegisterCommand(command *Command) {
+	for _, name := range append([]string{command.Name}, command.Aliases...) {
+		name = strings.ToLower(name)
+		if _, ok := commands[name]; ok {
+			panic(fmt.Sprintf("app/command: command %v registered twice", name))
+		}
+		commands[name] = command
+	}
+}
+
+// Commands returns the distinct commands available at the given rights level,
+// sorted by name.
+func Commands(rights int) []*Command {
+	var available []*Command
+	for name, command := range commands {
+		if name == command.Name && command.Rights <= rights {
+			available = append(available, command)
+		}
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i].Name < available[j].Name })
+	return available
+}
+
+func HandleCommandPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	p.RunCommand(buf.ReadString())
+	return nil
+}
+
+// RunCommand executes a command line without the leading ::, telling the
+// player about any problem with it.
+func (p *Player) RunCommand(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	command, ok := commands[strings.ToLower(fields[0])]
+	if !ok || command.Rights > p.Rights {
+		p.SendMessage(fmt.Sprintf("Unknown command: %v", fields[0]))
+		return
+	}
+	p.logger(worldLog).Info("command", "line", line)
+	args := &CommandArgs{world: p.world, args: fields[1:]}
+	if err := command.Handler(p, args); err != nil {
+		p.SendMessage(err.Error())
+		if _, ok := err.(CommandArgumentError); ok && command.Usage != "" {
+			p.SendMessage(fmt.Sprintf("Usage: ::%v %v", command.Name, command.Usage))
+		}
+	}
+}
+
+// CommandArgs gives typed access to the words following the command name.
+type CommandArgs struct {
+	world *World
+	args  []string
+}
+
+func (a *CommandArgs) Len() int {
+	return len(a.args)
+}
+
+func (a *CommandArgs) String(i int) (string, error) {
+	if i >= len(a.args) {
+		return "", CommandArgumentError{fmt.Sprintf("Missing argument %d.", i+1)}
+	}
+	return a.args[i], nil
+}
+
+// Rest joins the arguments from i onwards, for free text.
+func (a *CommandArgs) Rest(i int) string {
+	if i >= len(a.args) {
+		return ""
+	}
+	return strings.Join(a.args[i:], " ")
+}
+
+func (a *CommandArgs) Int(i int) (int, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, CommandArgumentError{fmt.Sprintf("%v is not a number.", s)}
+	}
+	return value, nil
+}
+
+// IntOr is Int with a default for an omitted argument.
+func (a *CommandArgs) IntOr(i, def int) (int, error) {
+	if i >= len(a.args) {
+		return def, nil
+	}
+	return a.Int(i)
+}
+
+// Player looks up an online player by username, with underscores standing in
+// for spaces.
+func (a *CommandArgs) Player(i int) (*Player, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return nil, err
+	}
+	username := strings.ReplaceAll(s, "_", " ")
+	p := a.world.PlayerByUsername(username)
+	if p == nil {
+		return nil, CommandError{fmt.Sprintf("%v is not online.", username)}
+	}
+	return p, nil
+}
+
+// Item looks up an item definition by ID or by name, with underscores
+// standing in for spaces.
+func (a *CommandArgs) Item(i int) (*ItemDefinition, error) {
+	s, err := a.String(i)
+	if err != nil {
+		return nil, err
+	}
+	if id, err := strconv.Atoi(s); err == nil {
+		if def := ItemDefinitionFor(id); def != nil {
+			return def, nil
+		}
+		return nil, CommandError{fmt.Sprintf("There is no item with id %d.", id)}
+	}
+	name := strings.ReplaceAll(s, "_", " ")
+	if def := ItemDefinitionByName(name); def != nil {
+		return def, nil
+	}
+	return nil, CommandError{fmt.Sprintf("There is no item called %v.", name)}
+}
+
+// Position reads x and y from arguments i and i+1, and an optional plane
+// from i+2.
+func (a *CommandArgs) Position(i int) (Position, error) {
+	x, err := a.Int(i)
+	if err != nil {
+		return Position{}, err
+	}
+	y, err := a.Int(i + 1)
+	if err != nil {
+		return Position{}, err
+	}
+	z, err := a.IntOr(i+2, 0)
+	if err != nil {
+		return Position{}, err
+	}
+	if x < 0 || y < 0 {
+		return Position{}, CommandArgumentError{fmt.Sprintf("%d, %d is not on the map.", x, y)}
+	}
+	if z < 0 || z > 3 {
+		return Position{}, CommandArgumentError{fmt.Sprintf("%d is not a valid plane.", z)}
+	}
+	return Position{X: x, Y: y, Z: z}, nil
+}
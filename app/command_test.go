@@ -0,0 +1,136 @@
+package app
+
+import (
+	"fmt"
+	rsio "rs-go-server/io"
+	"slices"
+	"testing"
+)
+
+// messages is the 253 frames sent by SendMessage for each line.
+func messages(lines ...string) []byte {
+	var data []byte
+	for _, line := range lines {
+		data = append(data, 253, byte(len(line)+1))
+		data = append(data, line...)
+		data = append(data, 10)
+	}
+	return data
+}
+
+// registerTestCommand adds a moderator command that echoes a position and
+// any text after it, and fails when that text is "fail".
+func registerTestCommand(t *testing.T) {
+	t.Helper()
+	command := &Command{
+		Name:    "echo",
+		Aliases: []string{"Say"},
+		Rights:  RIGHTS_MODERATOR,
+		Usage:   "x y [z] [text]",
+		Handler: func(p *Player, args *CommandArgs) error {
+			pos, err := args.Position(0)
+			if err != nil {
+				return err
+			}
+			if args.Rest(3) == "fail" {
+				return CommandError{"Failed."}
+			}
+			p.SendMessage(fmt.Sprintf("%d %d %d", pos.X, pos.Y, pos.Z))
+			if text := args.Rest(3); text != "" {
+				p.SendMessage(text)
+			}
+			return nil
+		},
+	}
+	RegisterCommand(command)
+	t.Cleanup(func() {
+		delete(commands, "echo")
+		delete(commands, "say")
+	})
+}
+
+func TestRunCommand(t *testing.T) {
+	registerTestCommand(t)
+	usage := "Usage: ::echo x y [z] [text]"
+	tests := []struct {
+		name     string
+		line     string
+		rights   int
+		expected []byte
+	}{
+		{"empty", "", RIGHTS_MODERATOR, nil},
+		{"blank", "   ", RIGHTS_MODERATOR, nil},
+		{"arguments", "echo 3222 3218 1 hello  there", RIGHTS_MODERATOR, messages("3222 3218 1", "hello there")},
+		{"optional argument", "echo 3222 3218", RIGHTS_ADMINISTRATOR, messages("3222 3218 0")},
+		{"case insensitive", "ECHO 1 2", RIGHTS_MODERATOR, messages("1 2 0")},
+		{"alias", "say 1 2", RIGHTS_MODERATOR, messages("1 2 0")},
+		{"insufficient rights", "echo 1 2", RIGHTS_PLAYER, messages("Unknown command: echo")},
+		{"unknown", "nosuch 1 2", RIGHTS_ADMINISTRATOR, messages("Unknown command: nosuch")},
+		{"missing argument", "echo 1", RIGHTS_MODERATOR, messages("Missing argument 2.", usage)},
+		{"not a number", "echo a 2", RIGHTS_MODERATOR, messages("a is not a number.", usage)},
+		{"off the map", "echo -1 2", RIGHTS_MODERATOR, messages("-1, 2 is not on the map.", usage)},
+		{"bad plane", "echo 1 2 4", RIGHTS_MODERATOR, messages("4 is not a valid plane.", usage)},
+		{"error without usage", "echo 1 2 0 fail", RIGHTS_MODERATOR, messages("Failed.")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, client := newTestPlayer(t)
+			p.world = newTestWorld()
+			p.Rights = test.rights
+			p.RunCommand(test.line)
+			expectSent(t, p, client, test.expected)
+		})
+	}
+}
+
+func TestCommandPacket(t *testing.T) {
+	registerTestCommand(t)
+	p, client := newTestPlayer(t)
+	p.world = newTestWorld()
+	p.Rights = RIGHTS_MODERATOR
+	data := rsio.NewByteBufferWithBytes([]byte("echo 5 6\n"))
+	data.Flip()
+	if err := dispatchPacket(p, &Packet{103, 9, data}); err != nil {
+		t.Fatal(err)
+	}
+	expectSent(t, p, client, messages("5 6 0"))
+}
+
+func TestCommandArgsPlayer(t *testing.T) {
+	players := crowd(t, 1)
+	players[0].Username = "Bob the cat"
+	args := &CommandArgs{world: players[0].world, args: []string{"bob_the_cat", "alice"}}
+	if p, err := args.Player(0); err != nil || p != players[0] {
+		t.Errorf("looking up bob_the_cat got %v, %v", p, err)
+	}
+	if _, err := args.Player(1); err != (CommandError{"alice is not online."}) {
+		t.Errorf("looking up an offline player got %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	names := func(rights int) []string {
+		var names []string
+		for _, command := range Commands(rights) {
+			names = append(names, command.Name)
+		}
+		return names
+	}
+	if expected := []string{"commands", "players", "pos"}; !slices.Equal(names(RIGHTS_PLAYER), expected) {
+		t.Errorf("player commands %v, want %v", names(RIGHTS_PLAYER), expected)
+	}
+	admin := names(RIGHTS_ADMINISTRATOR)
+	if !slices.IsSorted(admin) || !slices.Contains(admin, "kick") || !slices.Contains(admin, "tele") || slices.Contains(admin, "tp") {
+		t.Errorf("administrator commands %v", admin)
+	}
+}
+
+func TestRegisterCommandTwice(t *testing.T) {
+	t.Cleanup(func() { delete(commands, "unused") })
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a taken alias didn't panic")
+		}
+	}()
+	RegisterCommand(&Command{Name: "unused", Aliases: []string{"TP"}})
+}
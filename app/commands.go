@@ -0,0 +1,201 @@
+package app
+
+import (
+	"fmt"
+	"rs-go-server/logging"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterCommand(&Command{
+		Name:    "commands",
+		Aliases: []string{"help"},
+		Rights:  RIGHTS_PLAYER,
+		Handler: commandList,
+	})
+	RegisterCommand(&Command{
+		Name:    "pos",
+		Aliases: []string{"mypos", "coords"},
+		Rights:  RIGHTS_PLAYER,
+		Handler: commandPosition,
+	})
+	RegisterCommand(&Command{
+		Name:    "players",
+		Aliases: []string{"online"},
+		Rights:  RIGHTS_PLAYER,
+		Handler: commandPlayers,
+	})
+	RegisterCommand(&Command{
+		Name:    "kick",
+		Rights:  RIGHTS_MODERATOR,
+		Usage:   "username",
+		Handler: commandKick,
+	})
+	RegisterCommand(&Command{
+		Name:    "tele",
+		Aliases: []string{"teleport", "tp"},
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Usage:   "x y [z]",
+		Handler: commandTeleport,
+	})
+	RegisterCommand(&Command{
+		Name:    "item",
+		Aliases: []string{"pickup"},
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Usage:   "id|name [amount]",
+		Handler: commandItem,
+	})
+	RegisterCommand(&Command{
+		Name:    "bank",
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Handler: commandBank,
+	})
+	RegisterCommand(&Command{
+		Name:    "update",
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Usage:   "seconds",
+		Handler: commandUpdate,
+	})
+	RegisterCommand(&Command{
+		Name:    "trace",
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Usage:   "username",
+		Handler: commandTrace,
+	})
+	RegisterCommand(&Command{
+		Name:    "loglevel",
+		Rights:  RIGHTS_ADMINISTRATOR,
+		Usage:   "subsystem debug|info|warn|error",
+		Handler: commandLogLevel,
+	})
+}
+
+func commandList(p *Player, args *CommandArgs) error {
+	var names []string
+	for _, command := range Commands(p.Rights) {
+		names = append(names, command.Name)
+	}
+	p.SendMessage("Commands: " + strings.Join(names, ", "))
+	return nil
+}
+
+func commandPosition(p *Player, args *CommandArgs) error {
+	pos := p.Position
+	p.SendMessage(fmt.Sprintf("You are at %d, %d, %d (region %d, %d).", pos.X, pos.Y, pos.Z, pos.X>>3, pos.Y>>3))
+	return nil
+}
+
+func commandPlayers(p *Player, args *CommandArgs) error {
+	const listed = 20
+	players := p.world.Players()
+	p.SendMessage(fmt.Sprintf("There are %d players online.", len(players)))
+	var names []string
+	for i, other := range players {
+		if i == listed {
+			names = append(names, fmt.Sprintf("and %d more", len(players)-listed))
+			break
+		}
+		names = append(names, other.Username)
+	}
+	p.SendMessage(strings.Join(names, ", "))
+	return nil
+}
+
+func commandKick(p *Player, args *CommandArgs) error {
+	target, err := args.Player(0)
+	if err != nil {
+		return err
+	}
+	if target.Rights >= p.Rights && target != p {
+		return CommandError{fmt.Sprintf("You can't kick %v.", target.Username)}
+	}
+	p.world.disconnect(target)
+	p.SendMessage(fmt.Sprintf("Kicked %v.", target.Username))
+	return nil
+}
+
+func commandTeleport(p *Player, args *CommandArgs) error {
+	pos, err := args.Position(0)
+	if err != nil {
+		return err
+	}
+	p.Teleport(pos)
+	return nil
+}
+
+func commandItem(p *Player, args *CommandArgs) error {
+	def, err := args.Item(0)
+	if err != nil {
+		return err
+	}
+	amount, err := args.IntOr(1, 1)
+	if err != nil {
+		return err
+	}
+	if amount < 1 {
+		return CommandArgumentError{"The amount must be at least 1."}
+	}
+	if !p.Inventory.AddAll(Item{def.ID, amount}) {
+		return CommandError{"You don't have enough inventory space."}
+	}
+	return nil
+}
+
+func commandBank(p *Player, args *CommandArgs) error {
+	p.OpenBank()
+	return nil
+}
+
+func commandUpdate(p *Player, args *CommandArgs) error {
+	seconds, err := args.Int(0)
+	if err != nil {
+		return err
+	}
+	if seconds < 1 {
+		return CommandArgumentError{"The delay must be at least 1 second."}
+	}
+	if p.world.Updating() {
+		return CommandError{"An update is already in progress."}
+	}
+	p.world.SystemUpdate(time.Duration(seconds) * time.Second)
+	return nil
+}
+
+// commandTrace toggles packet tracing for a player.
+func commandTrace(p *Player, args *CommandArgs) error {
+	target, err := args.Player(0)
+	if err != nil {
+		return err
+	}
+	trace := !target.PacketTrace()
+	target.SetPacketTrace(trace)
+	if trace {
+		p.SendMessage(fmt.Sprintf("Tracing packets from %v.", target.Username))
+	} else {
+		p.SendMessage(fmt.Sprintf("Stopped tracing packets from %v.", target.Username))
+	}
+	return nil
+}
+
+func commandLogLevel(p *Player, args *CommandArgs) error {
+	name, err := args.String(0)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(logging.Subsystems(), name) {
+		return CommandArgumentError{fmt.Sprintf("There is no %v logger.", name)}
+	}
+	s, err := args.String(1)
+	if err != nil {
+		return err
+	}
+	level, err := logging.ParseLevel(s)
+	if err != nil {
+		return CommandArgumentError{fmt.Sprintf("%v is not a level.", s)}
+	}
+	logging.SetLevel(name, level)
+	p.SendMessage(fmt.Sprintf("The %v logger is now at %v.", name, level))
+	return nil
+}
@@ -0,0 +1,28 @@
+package app
+
+const (
+	INTERFACE_INVENTORY = 3214
+	INTERFACE_EQUIPMENT = 1688
+)
+
+// InterfaceListener keeps an item interface on the player's client in sync
+// with a container.  A few changed slots are sent on their own, larger
+// changes resend the whole container.
+type InterfaceListener struct {
+	Player      *Player
+	InterfaceID int
+}
+
+func NewInterfaceListener(p *Player, interfaceID int) *InterfaceListener {
+	return &InterfaceListener{Player: p, InterfaceID: interfaceID}
+}
+
+func (l *InterfaceListener) ContainerChanged(ic *ItemContainer, slots []int) {
+	// a single slot costs about as much as a slot of a full resend, so past
+	// half the container resending everything is just as cheap
+	if slots == nil || len(slots)*2 > ic.Size() {
+		l.Player.SendItems(l.InterfaceID, ic.Items())
+		return
+	}
+	l.Player.SendItemSlots(l.InterfaceID, ic, slots)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"fmt"
+	"rs-go-server/io"
+)
+
+// the text lines of the equipment screen's bonus panel, strength and prayer
+// skip the line used as the "Other bonuses" header
+var bonusInterfaces = [BONUS_COUNT]int{1675, 1676, 1677, 1678, 1679, 1680, 1681, 1682, 1683, 1684, 1686, 1687}
+
+var bonusNames = [BONUS_COUNT]string{
+	"Stab", "Slash", "Crush", "Magic", "Range",
+	"Stab", "Slash", "Crush", "Magic", "Range",
+	"Strength", "Prayer",
+}
+
+func init() {
+	RegisterPacketHandler(41, 6, HandleEquipPacket)
+}
+
+// HandleEquipPacket is the wield or wear option of an inventory item.
+func HandleEquipPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	id := int(buf.ReadShort(io.STANDARD, io.BIG))
+	slot := int(buf.ReadShort(io.A, io.BIG))
+	interfaceID := int(buf.ReadShort(io.A, io.BIG))
+	if interfaceID != INTERFACE_INVENTORY || p.Inventory.Get(slot).ID != id {
+		return nil
+	}
+	p.Equip(slot)
+	return nil
+}
+
+// Equip wears the item in an inventory slot, taking off whatever it replaces.
+func (p *Player) Equip(slot int) bool {
+	item := p.Inventory.Get(slot)
+	def := ItemDefinitionFor(item.ID)
+	if item.Empty() || def == nil || def.Equipment == nil {
+		p.SendMessage("You can't wear that.")
+		return false
+	}
+	equipment := def.Equipment
+	for skill, level := range equipment.Requirements {
+		if p.Skills.MaxLevel(skill) < level {
+			p.SendMessage(fmt.Sprintf("You need level %d %v to wear this.", level, SkillName(skill)))
+			return false
+		}
+	}
+
+	// ammo of the same kind stacks onto what's already worn
+	worn := p.Equipment.Get(equipment.Slot)
+	if worn.ID == item.ID && p.Equipment.Stackable(item.ID) {
+		added := p.Equipment.Add(item)
+		p.Inventory.RemoveFromSlot(slot, added)
+		p.equipmentChanged()
+		return true
+	}
+
+	removed := []int{equipment.Slot}
+	if equipment.TwoHanded {
+		removed = append(removed, EQUIPMENT_SHIELD)
+	} else if equipment.Slot == EQUIPMENT_SHIELD {
+		if weapon := p.equipped(EQUIPMENT_WEAPON); weapon != nil && weapon.TwoHanded {
+			removed = append(removed, EQUIPMENT_WEAPON)
+		}
+	}
+	equipped := p.Inventory.Transaction(func() bool {
+		return p.Equipment.Transaction(func() bool {
+			// the replaced item takes the slot the new one came from
+			p.Inventory.Set(slot, EmptyItem)
+			for _, equipmentSlot := range removed {
+				worn := p.Equipment.Get(equipmentSlot)
+				if worn.Empty() {
+					continue
+				}
+				p.Equipment.Set(equipmentSlot, EmptyItem)
+				if p.Inventory.Get(slot).Empty() && !p.Inventory.Stackable(worn.ID) {
+					p.Inventory.Set(slot, worn)
+				} else if !p.Inventory.AddAll(worn) {
+					return false
+				}
+			}
+			p.Equipment.Set(equipment.Slot, item)
+			return true
+		})
+	})
+	if !equipped {
+		p.SendMessage("You don't have enough free inventory space to do that.")
+		return false
+	}
+	p.equipmentChanged()
+	return true
+}
+
+// Unequip moves the item worn in slot back into the inventory.
+func (p *Player) Unequip(slot int) bool {
+	item := p.Equipment.Get(slot)
+	if item.Empty() {
+		return false
+	}
+	if !p.Inventory.AddAll(item) {
+		p.SendMessage("You don't have enough free inventory space to do that.")
+		return false
+	}
+	p.Equipment.Set(slot, EmptyItem)
+	p.equipmentChanged()
+	return true
+}
+
+// equipped returns the equipment definition of the item worn in slot, or nil.
+func (p *Player) equipped(slot int) *EquipmentDefinition {
+	if def := ItemDefinitionFor(p.Equipment.Get(slot).ID); def != nil {
+		return def.Equipment
+	}
+	return nil
+}
+
+// EquipmentBonuses sums the bonuses of everything worn.
+func (p *Player) EquipmentBonuses() [BONUS_COUNT]int {
+	var bonuses [BONUS_COUNT]int
+	for slot := 0; slot < EQUIPMENT_SIZE; slot++ {
+		if equipment := p.equipped(slot); equipment != nil {
+			for i, bonus := range equipment.Bonuses {
+				bonuses[i] += bonus
+			}
+		}
+	}
+	return bonuses
+}
+
+func (p *Player) SendEquipmentBonuses() {
+	for i, bonus := range p.EquipmentBonuses() {
+		p.SendInterfaceText(bonusInterfaces[i], fmt.Sprintf("%v: %+d", bonusNames[i], bonus))
+	}
+}
+
+// equipmentChanged shows the new gear to the player and everyone around.
+func (p *Player) equipmentChanged() {
+	p.SendEquipmentBonuses()
+	p.refreshAnimations()
+	p.UpdateFlags |= UPDATE_APPEARANCE
+}
+
+// refreshAnimations picks the standing and walking animations of the wielded
+// weapon.
+func (p *Player) refreshAnimations() {
+	p.Animations = DefaultMovementAnimations
+	if weapon := p.equipped(EQUIPMENT_WEAPON); weapon != nil && weapon.Animations != nil {
+		p.Animations = *weapon.Animations
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import "testing"
+
+func TestWeaponAnimations(t *testing.T) {
+	if err := LoadItemDefinitions("../data/items.json"); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := newTestPlayer(t)
+	p.Skills[ATTACK] = Skill{Level: 99, Experience: ExperienceForLevel(99)}
+
+	for _, id := range []int{4151, 1319, 1333} {
+		p.Inventory.Set(0, Item{id, 1})
+		if !p.Equip(0) {
+			t.Fatalf("couldn't equip %d", id)
+		}
+		want := DefaultMovementAnimations
+		if animations := ItemDefinitionFor(id).Equipment.Animations; animations != nil {
+			want = *animations
+		}
+		if p.Animations != want {
+			t.Errorf("animations with %d wielded are %+v, want %+v", id, p.Animations, want)
+		}
+		if p.Animations == DefaultMovementAnimations && id != 1333 {
+			t.Errorf("%d has no animations of its own", id)
+		}
+		p.Inventory.Clear()
+	}
+
+	p.Unequip(EQUIPMENT_WEAPON)
+	if p.Animations != DefaultMovementAnimations {
+		t.Errorf("animations with nothing wielded are %+v, want the defaults", p.Animations)
+	}
+}
@@ -1,6 +1,13 @@
 package app
 
 type Item struct {
-	ID int
+	ID     int
 	Amount int
 }
+
+// EmptyItem fills container slots that hold nothing.
+var EmptyItem = Item{ID: -1, Amount: 0}
+
+func (i Item) Empty() bool {
+	return i.ID == -1
+}
@@ -1,31 +1,321 @@
 package app
 
-type ItemContainer []*Item
+import (
+	"math"
+	"slices"
+)
 
-func NewItemContainer(size int) ItemContainer {
-	slice := make([]*Item, size)
-	for i := range slice {
-		slice[i] = &Item { -1, 0 }
+type StackMode int
+
+const (
+	STACK_DEFINITION StackMode = iota // stack items whose definition says so
+	STACK_ALWAYS                      // every item stacks, like the bank
+	STACK_NEVER                       // one item per slot
+)
+
+// MaxStackAmount is the most of one item a slot can hold, the client reads
+// amounts as a signed int.
+const MaxStackAmount = math.MaxInt32
+
+// ContainerListener is told which slots of a container changed since the last
+// notification, slots is nil when the whole container needs resending.
+type ContainerListener interface {
+	ContainerChanged(ic *ItemContainer, slots []int)
+}
+
+// ItemContainer holds the items of an inventory, equipment, bank and so on.
+// Changes are tracked per slot and handed to the listeners in one go by
+// NotifyListeners, once per cycle.
+type ItemContainer struct {
+	items     []Item
+	Mode      StackMode
+	dirty     []bool
+	refresh   bool
+	listeners []ContainerListener
+}
+
+func NewItemContainer(size int, mode StackMode) *ItemContainer {
+	ic := &ItemContainer{items: make([]Item, size), Mode: mode, dirty: make([]bool, size)}
+	for i := range ic.items {
+		ic.items[i] = EmptyItem
+	}
+	return ic
+}
+
+func (ic *ItemContainer) Size() int {
+	return len(ic.items)
+}
+
+func (ic *ItemContainer) Get(slot int) Item {
+	if slot < 0 || slot >= len(ic.items) {
+		return EmptyItem
+	}
+	return ic.items[slot]
+}
+
+func (ic *ItemContainer) Set(slot int, item Item) {
+	if item.Amount <= 0 {
+		item = EmptyItem
+	}
+	if ic.items[slot] != item {
+		ic.items[slot] = item
+		ic.dirty[slot] = true
+	}
+}
+
+func (ic *ItemContainer) Clear() {
+	for i := range ic.items {
+		ic.Set(i, EmptyItem)
+	}
+}
+
+// Items returns a copy of every slot, empty slots included.
+func (ic *ItemContainer) Items() []Item {
+	return append([]Item(nil), ic.items...)
+}
+
+func (ic *ItemContainer) Stackable(id int) bool {
+	switch ic.Mode {
+	case STACK_ALWAYS:
+		return true
+	case STACK_NEVER:
+		return false
 	}
-	return slice
+	return IsStackable(id)
 }
 
-func (ic ItemContainer) Add(item *Item) bool {
-	for idx, i := range ic {
-		if i.ID == -1 {
-			ic[idx] = item
-			return true
+// FreeSlot returns the first empty slot, or -1 if the container is full.
+func (ic *ItemContainer) FreeSlot() int {
+	for i, item := range ic.items {
+		if item.Empty() {
+			return i
 		}
 	}
-	return false
+	return -1
+}
+
+func (ic *ItemContainer) FreeSlots() int {
+	free := 0
+	for _, item := range ic.items {
+		if item.Empty() {
+			free++
+		}
+	}
+	return free
+}
+
+// SlotOf returns the first slot holding id, or -1.
+func (ic *ItemContainer) SlotOf(id int) int {
+	for i, item := range ic.items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ic *ItemContainer) Count(id int) int {
+	count := 0
+	for _, item := range ic.items {
+		if item.ID == id {
+			count += item.Amount
+		}
+	}
+	return count
+}
+
+func (ic *ItemContainer) Contains(id, amount int) bool {
+	return ic.Count(id) >= amount
+}
+
+// CanAdd reports whether all of item would fit.
+func (ic *ItemContainer) CanAdd(item Item) bool {
+	if item.ID < 0 || item.Amount <= 0 {
+		return false
+	}
+	if ic.Stackable(item.ID) {
+		if slot := ic.SlotOf(item.ID); slot != -1 {
+			return ic.items[slot].Amount <= MaxStackAmount-item.Amount
+		}
+		return ic.FreeSlot() != -1
+	}
+	return ic.FreeSlots() >= item.Amount
+}
+
+// Add adds as much of item as fits, merging with an existing stack when the
+// item is stackable, and returns the amount added.
+func (ic *ItemContainer) Add(item Item) int {
+	if item.ID < 0 || item.Amount <= 0 {
+		return 0
+	}
+	if ic.Stackable(item.ID) {
+		slot := ic.SlotOf(item.ID)
+		if slot == -1 {
+			if slot = ic.FreeSlot(); slot == -1 {
+				return 0
+			}
+			ic.items[slot] = Item{ID: item.ID}
+		}
+		existing := ic.items[slot].Amount
+		added := min(item.Amount, MaxStackAmount-existing)
+		ic.Set(slot, Item{ID: item.ID, Amount: existing + added})
+		return added
+	}
+	added := 0
+	for ; added < item.Amount; added++ {
+		slot := ic.FreeSlot()
+		if slot == -1 {
+			break
+		}
+		ic.Set(slot, Item{ID: item.ID, Amount: 1})
+	}
+	return added
+}
+
+// AddAll adds all of item or, if it doesn't fit, nothing at all.
+func (ic *ItemContainer) AddAll(item Item) bool {
+	if !ic.CanAdd(item) {
+		return false
+	}
+	ic.Add(item)
+	return true
+}
+
+// Remove takes up to item.Amount of item.ID out of the container, from the
+// first slots holding it, and returns the amount removed.
+func (ic *ItemContainer) Remove(item Item) int {
+	removed := 0
+	for slot := range ic.items {
+		if removed == item.Amount {
+			break
+		}
+		if ic.items[slot].ID != item.ID {
+			continue
+		}
+		removed += ic.RemoveFromSlot(slot, item.Amount-removed)
+	}
+	return removed
+}
+
+// RemoveAll removes all of item or, if there isn't enough of it, nothing.
+func (ic *ItemContainer) RemoveAll(item Item) bool {
+	if item.Amount <= 0 || !ic.Contains(item.ID, item.Amount) {
+		return false
+	}
+	ic.Remove(item)
+	return true
+}
+
+// RemoveFromSlot takes up to amount out of a single slot and returns the
+// amount removed.
+func (ic *ItemContainer) RemoveFromSlot(slot, amount int) int {
+	item := ic.Get(slot)
+	if item.Empty() || amount <= 0 {
+		return 0
+	}
+	removed := min(amount, item.Amount)
+	ic.Set(slot, Item{ID: item.ID, Amount: item.Amount - removed})
+	return removed
+}
+
+func (ic *ItemContainer) Swap(a, b int) {
+	if a < 0 || b < 0 || a >= len(ic.items) || b >= len(ic.items) {
+		return
+	}
+	first, second := ic.items[a], ic.items[b]
+	ic.Set(a, second)
+	ic.Set(b, first)
+}
+
+// Insert moves the item in slot from to slot to, shifting the items in
+// between over by one.
+func (ic *ItemContainer) Insert(from, to int) {
+	if from < 0 || to < 0 || from >= len(ic.items) || to >= len(ic.items) {
+		return
+	}
+	for from < to {
+		ic.Swap(from, from+1)
+		from++
+	}
+	for from > to {
+		ic.Swap(from, from-1)
+		from--
+	}
+}
+
+// Shift moves every item towards the start, closing any gaps while keeping
+// their order.
+func (ic *ItemContainer) Shift() {
+	next := 0
+	for slot, item := range ic.items {
+		if item.Empty() {
+			continue
+		}
+		if slot != next {
+			ic.Set(next, item)
+			ic.Set(slot, EmptyItem)
+		}
+		next++
+	}
 }
 
-func (ic ItemContainer) RemoveFirst(id int) bool {
-	for idx, i := range ic {
-		if i.ID == id {
-			ic[idx] = &Item{ -1, 0 }
-			return true
+// Transaction runs fn and puts the contents back as they were if it returns
+// false, for operations that touch several items at once.
+func (ic *ItemContainer) Transaction(fn func() bool) bool {
+	saved := ic.Items()
+	if fn() {
+		return true
+	}
+	for slot, item := range saved {
+		if ic.items[slot] != item {
+			ic.Set(slot, item)
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// AddListener registers listener, once however many times it's added.
+func (ic *ItemContainer) AddListener(listener ContainerListener) {
+	if slices.Contains(ic.listeners, listener) {
+		return
+	}
+	ic.listeners = append(ic.listeners, listener)
+}
+
+func (ic *ItemContainer) RemoveListener(listener ContainerListener) {
+	for i, l := range ic.listeners {
+		if l == listener {
+			ic.listeners = append(ic.listeners[:i], ic.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// Refresh makes the next notification resend the whole container, for when
+// a listener has just been added or the client lost track of it.
+func (ic *ItemContainer) Refresh() {
+	ic.refresh = true
+}
+
+// NotifyListeners passes the slots changed since the last call on to every
+// listener.
+func (ic *ItemContainer) NotifyListeners() {
+	var slots []int
+	if !ic.refresh {
+		for slot, dirty := range ic.dirty {
+			if dirty {
+				slots = append(slots, slot)
+			}
+		}
+		if len(slots) == 0 {
+			return
+		}
+	}
+	for _, listener := range ic.listeners {
+		listener.ContainerChanged(ic, slots)
+	}
+	ic.refresh = false
+	for slot := range ic.dirty {
+		ic.dirty[slot] = false
+	}
+}
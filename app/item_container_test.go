@@ -0,0 +1,201 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestContainerAdd(t *testing.T) {
+	if err := LoadItemDefinitions("../data/items.json"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		mode     StackMode
+		add      []Item
+		added    []int
+		expected []Item
+	}{
+		{"stacks merge", STACK_ALWAYS, []Item{{5, 10}, {6, 1}, {5, 5}}, []int{10, 1, 5}, []Item{{5, 15}, {6, 1}, EmptyItem}},
+		{"one per slot", STACK_NEVER, []Item{{5, 2}, {5, 2}}, []int{2, 1}, []Item{{5, 1}, {5, 1}, {5, 1}}},
+		{"stackable by definition", STACK_DEFINITION, []Item{{892, 100}, {892, 50}}, []int{100, 50}, []Item{{892, 150}, EmptyItem, EmptyItem}},
+		{"unstackable by definition", STACK_DEFINITION, []Item{{385, 2}}, []int{2}, []Item{{385, 1}, {385, 1}, EmptyItem}},
+		{"stack fills the last slot", STACK_ALWAYS, []Item{{1, 1}, {2, 1}, {3, 1}, {4, 1}}, []int{1, 1, 1, 0}, []Item{{1, 1}, {2, 1}, {3, 1}}},
+		{"invalid items", STACK_ALWAYS, []Item{{-1, 1}, {5, 0}, {5, -3}}, []int{0, 0, 0}, []Item{EmptyItem, EmptyItem, EmptyItem}},
+		{"overflow", STACK_ALWAYS, []Item{{5, MaxStackAmount - 1}, {5, 10}, {5, 1}}, []int{MaxStackAmount - 1, 1, 0}, []Item{{5, MaxStackAmount}, EmptyItem, EmptyItem}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ic := NewItemContainer(3, test.mode)
+			for i, item := range test.add {
+				if added := ic.Add(item); added != test.added[i] {
+					t.Errorf("adding %+v added %d, want %d", item, added, test.added[i])
+				}
+			}
+			if !slices.Equal(ic.Items(), test.expected) {
+				t.Errorf("contents %+v, want %+v", ic.Items(), test.expected)
+			}
+		})
+	}
+}
+
+func TestContainerCanAdd(t *testing.T) {
+	ic := NewItemContainer(2, STACK_ALWAYS)
+	ic.Add(Item{5, MaxStackAmount - 10})
+	tests := []struct {
+		item Item
+		can  bool
+	}{
+		{Item{5, 10}, true},
+		{Item{5, 11}, false},
+		{Item{6, 1}, true},
+		{Item{6, 0}, false},
+		{Item{-1, 1}, false},
+	}
+	for _, test := range tests {
+		if can := ic.CanAdd(test.item); can != test.can {
+			t.Errorf("CanAdd(%+v) = %v, want %v", test.item, can, test.can)
+		}
+	}
+
+	before := ic.Items()
+	if ic.AddAll(Item{5, 11}) {
+		t.Error("AddAll added past the stack limit")
+	}
+	if !slices.Equal(ic.Items(), before) {
+		t.Errorf("a failed AddAll changed the contents to %+v", ic.Items())
+	}
+
+	never := NewItemContainer(3, STACK_NEVER)
+	never.Add(Item{1, 1})
+	if never.CanAdd(Item{2, 3}) || !never.CanAdd(Item{2, 2}) {
+		t.Error("CanAdd doesn't count free slots for unstackable items")
+	}
+}
+
+func TestContainerRemove(t *testing.T) {
+	ic := NewItemContainer(4, STACK_NEVER)
+	ic.Add(Item{5, 3})
+	ic.Add(Item{6, 1})
+
+	if removed := ic.Remove(Item{5, 2}); removed != 2 {
+		t.Errorf("removed %d, want 2", removed)
+	}
+	if expected := []Item{EmptyItem, EmptyItem, {5, 1}, {6, 1}}; !slices.Equal(ic.Items(), expected) {
+		t.Errorf("contents %+v, want %+v", ic.Items(), expected)
+	}
+	if removed := ic.Remove(Item{5, 5}); removed != 1 {
+		t.Errorf("removed %d of the last one, want 1", removed)
+	}
+
+	if ic.RemoveAll(Item{6, 2}) {
+		t.Error("RemoveAll removed more than there was")
+	}
+	if ic.Count(6) != 1 {
+		t.Error("a failed RemoveAll changed the contents")
+	}
+	if !ic.RemoveAll(Item{6, 1}) || ic.Count(6) != 0 {
+		t.Error("RemoveAll didn't remove the item")
+	}
+
+	stacks := NewItemContainer(2, STACK_ALWAYS)
+	stacks.Add(Item{5, 10})
+	if removed := stacks.RemoveFromSlot(0, 4); removed != 4 || stacks.Get(0) != (Item{5, 6}) {
+		t.Errorf("RemoveFromSlot removed %d leaving %+v", removed, stacks.Get(0))
+	}
+	if removed := stacks.RemoveFromSlot(0, 100); removed != 6 || !stacks.Get(0).Empty() {
+		t.Errorf("RemoveFromSlot removed %d leaving %+v", removed, stacks.Get(0))
+	}
+	if removed := stacks.RemoveFromSlot(5, 1); removed != 0 {
+		t.Error("RemoveFromSlot removed from a slot out of range")
+	}
+}
+
+func TestContainerTransaction(t *testing.T) {
+	ic := NewItemContainer(3, STACK_ALWAYS)
+	ic.Add(Item{5, 10})
+	before := ic.Items()
+
+	ok := ic.Transaction(func() bool {
+		ic.Remove(Item{5, 10})
+		ic.Add(Item{6, 1})
+		ic.Add(Item{7, 1})
+		return false
+	})
+	if ok || !slices.Equal(ic.Items(), before) {
+		t.Errorf("rolled back transaction left %+v, want %+v", ic.Items(), before)
+	}
+
+	ok = ic.Transaction(func() bool {
+		ic.Remove(Item{5, 4})
+		return true
+	})
+	if !ok || ic.Count(5) != 6 {
+		t.Errorf("committed transaction left %+v", ic.Items())
+	}
+}
+
+func TestContainerRearrange(t *testing.T) {
+	items := []Item{{1, 1}, EmptyItem, {3, 1}, {4, 1}}
+	fill := func() *ItemContainer {
+		ic := NewItemContainer(len(items), STACK_NEVER)
+		for slot, item := range items {
+			ic.Set(slot, item)
+		}
+		return ic
+	}
+	tests := []struct {
+		name      string
+		rearrange func(ic *ItemContainer)
+		expected  []Item
+	}{
+		{"swap", func(ic *ItemContainer) { ic.Swap(0, 3) }, []Item{{4, 1}, EmptyItem, {3, 1}, {1, 1}}},
+		{"swap out of range", func(ic *ItemContainer) { ic.Swap(0, 4) }, items},
+		{"insert forwards", func(ic *ItemContainer) { ic.Insert(0, 2) }, []Item{EmptyItem, {3, 1}, {1, 1}, {4, 1}}},
+		{"insert backwards", func(ic *ItemContainer) { ic.Insert(3, 0) }, []Item{{4, 1}, {1, 1}, EmptyItem, {3, 1}}},
+		{"shift", func(ic *ItemContainer) { ic.Shift() }, []Item{{1, 1}, {3, 1}, {4, 1}, EmptyItem}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ic := fill()
+			test.rearrange(ic)
+			if !slices.Equal(ic.Items(), test.expected) {
+				t.Errorf("contents %+v, want %+v", ic.Items(), test.expected)
+			}
+		})
+	}
+}
+
+// recordingListener keeps the slots of every notification.
+type recordingListener struct{ notifications [][]int }
+
+func (l *recordingListener) ContainerChanged(ic *ItemContainer, slots []int) {
+	l.notifications = append(l.notifications, slots)
+}
+
+func TestContainerListeners(t *testing.T) {
+	ic := NewItemContainer(4, STACK_ALWAYS)
+	listener := &recordingListener{}
+	ic.AddListener(listener)
+	ic.AddListener(listener)
+
+	ic.Add(Item{5, 1})
+	ic.Set(2, Item{6, 1})
+	ic.NotifyListeners()
+	ic.NotifyListeners() // nothing changed since
+	ic.Refresh()
+	ic.NotifyListeners()
+	ic.RemoveListener(listener)
+	ic.Set(3, Item{7, 1})
+	ic.NotifyListeners()
+
+	expected := [][]int{{0, 2}, nil}
+	if len(listener.notifications) != len(expected) {
+		t.Fatalf("notified %v, want %v", listener.notifications, expected)
+	}
+	for i := range expected {
+		if !slices.Equal(listener.notifications[i], expected[i]) {
+			t.Errorf("notified %v, want %v", listener.notifications, expected)
+		}
+	}
+}
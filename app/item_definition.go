@@ -0,0 +1,281 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// equipment bonuses, in the order of the equipment screen
+	BONUS_STAB_ATTACK = iota
+	BONUS_SLASH_ATTACK
+	BONUS_CRUSH_ATTACK
+	BONUS_MAGIC_ATTACK
+	BONUS_RANGED_ATTACK
+	BONUS_STAB_DEFENCE
+	BONUS_SLASH_DEFENCE
+	BONUS_CRUSH_DEFENCE
+	BONUS_MAGIC_DEFENCE
+	BONUS_RANGED_DEFENCE
+	BONUS_STRENGTH
+	BONUS_PRAYER
+
+	BONUS_COUNT = 12
+)
+
+// NOTE_EXAMINE is the examine text of every bank note.
+const NOTE_EXAMINE = "Swap this note at any bank for the equivalent item."
+
+var equipmentSlotNames = map[string]int{
+	"hat":    EQUIPMENT_HAT,
+	"cape":   EQUIPMENT_CAPE,
+	"amulet": EQUIPMENT_AMULET,
+	"weapon": EQUIPMENT_WEAPON,
+	"chest":  EQUIPMENT_CHEST,
+	"shield": EQUIPMENT_SHIELD,
+	"legs":   EQUIPMENT_LEGS,
+	"hands":  EQUIPMENT_HANDS,
+	"feet":   EQUIPMENT_FEET,
+	"ring":   EQUIPMENT_RING,
+	"arrows": EQUIPMENT_ARROWS,
+}
+
+type InvalidItemDefinitionError struct {
+	ID     int
+	Reason string
+}
+
+func (e InvalidItemDefinitionError) Error() string {
+	return fmt.Sprintf("app/item_definition: invalid definition.  ID: %d, Reason: %s", e.ID, e.Reason)
+}
+
+type ItemDefinition struct {
+	ID        int
+	Name      string
+	Examine   string
+	Stackable bool
+	Members   bool
+	Value     int
+	Weight    float64 // in kilograms
+	// Noted is set for bank notes, NoteID links a note and its item both ways
+	// and is -1 for items without one.
+	Noted     bool
+	NoteID    int
+	Equipment *EquipmentDefinition // nil for items that can't be worn
+}
+
+type EquipmentDefinition struct {
+	Slot    int
+	Bonuses [BONUS_COUNT]int
+	// Requirements is the level needed in each skill to wear the item
+	Requirements [SKILL_COUNT]int
+	TwoHanded    bool
+	// FullBody hides the arms, FullHelm the hair and FullMask the beard
+	FullBody bool
+	FullHelm bool
+	FullMask bool
+	// Animations replace the default standing and walking animations while a
+	// weapon is wielded, nil to keep them
+	Animations *MovementAnimations
+}
+
+// itemDefinitionRecord is an item in the data file.  Notes aren't listed on
+// their own, an item names its note and the note's definition is derived.
+type itemDefinitionRecord struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	Examine   string           `json:"examine"`
+	Stackable bool             `json:"stackable"`
+	Members   bool             `json:"members"`
+	Value     int              `json:"value"`
+	Weight    float64          `json:"weight"`
+	Note      *int             `json:"note"`
+	Equipment *equipmentRecord `json:"equipment"`
+}
+
+type equipmentRecord struct {
+	Slot         string         `json:"slot"`
+	Bonuses      []int          `json:"bonuses"`
+	Requirements map[string]int `json:"requirements"`
+	TwoHanded    bool           `json:"twoHanded"`
+	FullBody     bool           `json:"fullBody"`
+	FullHelm     bool           `json:"fullHelm"`
+	FullMask     bool           `json:"fullMask"`
+	Animations   map[string]int `json:"animations"`
+}
+
+var itemDefinitions = make(map[int]*ItemDefinition)
+
+// RegisterItemDefinition adds def, replacing any definition with its ID.
+func RegisterItemDefinition(def *ItemDefinition) {
+	itemDefinitions[def.ID] = def
+}
+
+// ItemDefinitionFor returns the definition of an item, or nil if unknown.
+func ItemDefinitionFor(id int) *ItemDefinition {
+	return itemDefinitions[id]
+}
+
+// ItemDefinitionByName finds an item by name, ignoring case.  Items come
+// before their notes and lower IDs before higher ones when names are shared.
+func ItemDefinitionByName(name string) *ItemDefinition {
+	var found *ItemDefinition
+	for _, def := range itemDefinitions {
+		if !strings.EqualFold(def.Name, name) {
+			continue
+		}
+		if found == nil || (found.Noted && !def.Noted) || (found.Noted == def.Noted && def.ID < found.ID) {
+			found = def
+		}
+	}
+	return found
+}
+
+func ItemDefinitionCount() int {
+	return len(itemDefinitions)
+}
+
+// IsStackable reports whether any number of the item fits in one slot,
+// unknown items don't stack.
+func IsStackable(id int) bool {
+	def := ItemDefinitionFor(id)
+	return def != nil && def.Stackable
+}
+
+// NotedID returns the note of an item, or id itself when it has no note or
+// is a note already.
+func NotedID(id int) int {
+	if def := ItemDefinitionFor(id); def != nil && !def.Noted && def.NoteID != -1 {
+		return def.NoteID
+	}
+	return id
+}
+
+// UnnotedID returns the item a note stands for, or id itself when it isn't a
+// note.
+func UnnotedID(id int) int {
+	if def := ItemDefinitionFor(id); def != nil && def.Noted {
+		return def.NoteID
+	}
+	return id
+}
+
+// LoadItemDefinitions registers the items in a JSON data file, along with
+// their notes.  Definitions already loaded, for instance from the cache, are
+// replaced.
+func LoadItemDefinitions(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var records []itemDefinitionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		def := &ItemDefinition{
+			ID:        record.ID,
+			Name:      record.Name,
+			Examine:   record.Examine,
+			Stackable: record.Stackable,
+			Members:   record.Members,
+			Value:     record.Value,
+			Weight:    record.Weight,
+			NoteID:    -1,
+		}
+		if record.Equipment != nil {
+			equipment, err := record.Equipment.definition(record.ID)
+			if err != nil {
+				return err
+			}
+			def.Equipment = equipment
+		}
+		RegisterItemDefinition(def)
+		if record.Note != nil {
+			def.NoteID = *record.Note
+			RegisterItemDefinition(&ItemDefinition{
+				ID:        def.NoteID,
+				Name:      def.Name,
+				Examine:   NOTE_EXAMINE,
+				Stackable: true,
+				Members:   def.Members,
+				Value:     def.Value,
+				Noted:     true,
+				NoteID:    def.ID,
+			})
+		}
+	}
+	return nil
+}
+
+func (r *equipmentRecord) definition(id int) (*EquipmentDefinition, error) {
+	slot, ok := equipmentSlotNames[r.Slot]
+	if !ok {
+		return nil, InvalidItemDefinitionError{id, fmt.Sprintf("unknown equipment slot %q", r.Slot)}
+	}
+	if r.Bonuses != nil && len(r.Bonuses) != BONUS_COUNT {
+		return nil, InvalidItemDefinitionError{id, fmt.Sprintf("%d bonuses instead of %d", len(r.Bonuses), BONUS_COUNT)}
+	}
+	if r.TwoHanded && slot != EQUIPMENT_WEAPON {
+		return nil, InvalidItemDefinitionError{id, "two-handed item that isn't a weapon"}
+	}
+	equipment := &EquipmentDefinition{
+		Slot:      slot,
+		TwoHanded: r.TwoHanded,
+		FullBody:  r.FullBody,
+		FullHelm:  r.FullHelm,
+		FullMask:  r.FullMask,
+	}
+	copy(equipment.Bonuses[:], r.Bonuses)
+	if r.Animations != nil {
+		if slot != EQUIPMENT_WEAPON {
+			return nil, InvalidItemDefinitionError{id, "animations on an item that isn't a weapon"}
+		}
+		animations := DefaultMovementAnimations
+		for name, animation := range r.Animations {
+			field := animations.field(name)
+			if field == nil {
+				return nil, InvalidItemDefinitionError{id, fmt.Sprintf("unknown animation %q", name)}
+			}
+			*field = animation
+		}
+		equipment.Animations = &animations
+	}
+	for name, level := range r.Requirements {
+		skill := SkillByName(name)
+		if skill == -1 {
+			return nil, InvalidItemDefinitionError{id, fmt.Sprintf("requirement of unknown skill %q", name)}
+		}
+		equipment.Requirements[skill] = level
+	}
+	return equipment, nil
+}
+
+// ValidateItemDefinitions checks that every definition is complete and that
+// the IDs they reference exist, returning all problems found.
+func ValidateItemDefinitions() error {
+	var errs []error
+	for id, def := range itemDefinitions {
+		switch {
+		case id < 0:
+			errs = append(errs, InvalidItemDefinitionError{id, "negative id"})
+		case def.Name == "":
+			errs = append(errs, InvalidItemDefinitionError{id, "missing name"})
+		case def.Value < 0:
+			errs = append(errs, InvalidItemDefinitionError{id, "negative value"})
+		case def.NoteID != -1:
+			other := ItemDefinitionFor(def.NoteID)
+			if other == nil {
+				errs = append(errs, InvalidItemDefinitionError{id, fmt.Sprintf("note %d doesn't exist", def.NoteID)})
+			} else if other.NoteID != id || other.Noted == def.Noted {
+				errs = append(errs, InvalidItemDefinitionError{id, fmt.Sprintf("note %d doesn't link back", def.NoteID)})
+			}
+		case def.Noted:
+			errs = append(errs, InvalidItemDefinitionError{id, "note without an item"})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"rs-go-server/io"
+)
+
+// NOTE_TEMPLATE is the item every bank note in the cache is drawn from.
+const NOTE_TEMPLATE = 799
+
+type InvalidItemCacheError struct {
+	ID     int
+	Opcode int
+}
+
+func (e InvalidItemCacheError) Error() string {
+	return fmt.Sprintf("app/item_definition_cache: unknown opcode.  ID: %d, Opcode: %d", e.ID, e.Opcode)
+}
+
+// LoadItemDefinitionsFromCache registers the items in obj.dat and obj.idx,
+// as extracted from the client cache's config archive.  The cache knows
+// names, stacking, value and notes, but nothing about equipment, so the data
+// file is meant to be loaded on top of it.
+func LoadItemDefinitionsFromCache(datPath, idxPath string) error {
+	dat, err := os.ReadFile(datPath)
+	if err != nil {
+		return err
+	}
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return err
+	}
+	index := io.NewInBuffer(io.NewByteBufferWithBytes(idx))
+	index.Buffer.Flip()
+	count := int(index.ReadShort(io.STANDARD, io.BIG))
+	// the first two bytes of obj.dat repeat the count
+	offset := 2
+	definitions := make([]*ItemDefinition, 0, count)
+	for id := 0; id < count && index.Remaining() >= 2; id++ {
+		size := int(index.ReadShort(io.STANDARD, io.BIG))
+		if offset+size > len(dat) {
+			return InvalidItemDefinitionError{id, "entry runs past the end of obj.dat"}
+		}
+		def, err := decodeCachedItem(id, dat[offset:offset+size])
+		if err != nil {
+			return err
+		}
+		definitions = append(definitions, def)
+		offset += size
+	}
+
+	// notes only name the item they stand for, they take everything else from
+	// it
+	for _, def := range definitions {
+		if !def.Noted {
+			continue
+		}
+		if def.NoteID < 0 || def.NoteID >= len(definitions) {
+			return InvalidItemDefinitionError{def.ID, fmt.Sprintf("note of unknown item %d", def.NoteID)}
+		}
+		item := definitions[def.NoteID]
+		item.NoteID = def.ID
+		def.Name = item.Name
+		def.Members = item.Members
+		def.Value = item.Value
+		def.Examine = NOTE_EXAMINE
+	}
+	for _, def := range definitions {
+		RegisterItemDefinition(def)
+	}
+	return nil
+}
+
+func decodeCachedItem(id int, data []byte) (*ItemDefinition, error) {
+	def := &ItemDefinition{ID: id, Value: 1, NoteID: -1}
+	buf := io.NewInBuffer(io.NewByteBufferWithBytes(data))
+	buf.Buffer.Flip()
+	noteTemplate := -1
+	skip := func(amount int) {
+		buf.ReadBytes(amount, io.STANDARD)
+	}
+decode:
+	for buf.Remaining() > 0 {
+		opcode := int(buf.ReadByte(io.STANDARD))
+		switch {
+		case opcode == 0:
+			break decode
+		case opcode == 2:
+			def.Name = buf.ReadString()
+		case opcode == 3:
+			def.Examine = buf.ReadString()
+		case opcode == 11:
+			def.Stackable = true
+		case opcode == 12:
+			def.Value = int(int32(buf.ReadInt(io.STANDARD, io.BIG)))
+		case opcode == 16:
+			def.Members = true
+		case opcode == 23 || opcode == 25:
+			skip(3)
+		case opcode >= 30 && opcode < 40:
+			buf.ReadString()
+		case opcode == 40:
+			skip(int(buf.ReadByte(io.STANDARD)) * 4)
+		case opcode == 97:
+			def.NoteID = int(buf.ReadShort(io.STANDARD, io.BIG))
+		case opcode == 98:
+			noteTemplate = int(buf.ReadShort(io.STANDARD, io.BIG))
+		case opcode >= 100 && opcode < 110:
+			skip(4)
+		case opcode == 113 || opcode == 114 || opcode == 115:
+			skip(1)
+		case opcode == 1 || (opcode >= 4 && opcode <= 10) || opcode == 24 || opcode == 26 ||
+			opcode == 78 || opcode == 79 || (opcode >= 90 && opcode <= 95) || (opcode >= 110 && opcode <= 112):
+			skip(2)
+		default:
+			return nil, InvalidItemCacheError{id, opcode}
+		}
+	}
+	if noteTemplate == NOTE_TEMPLATE && def.NoteID != -1 {
+		def.Noted = true
+		def.Stackable = true
+	} else {
+		def.NoteID = -1
+	}
+	return def, nil
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeItemCache writes obj.dat and obj.idx holding the given encoded items.
+func writeItemCache(t *testing.T, entries ...[]byte) (string, string) {
+	t.Helper()
+	dat := []byte{0, byte(len(entries))}
+	idx := []byte{0, byte(len(entries))}
+	for _, entry := range entries {
+		dat = append(dat, entry...)
+		idx = append(idx, byte(len(entry)>>8), byte(len(entry)))
+	}
+	dir := t.TempDir()
+	datPath, idxPath := filepath.Join(dir, "obj.dat"), filepath.Join(dir, "obj.idx")
+	if err := os.WriteFile(datPath, dat, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(idxPath, idx, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return datPath, idxPath
+}
+
+func TestLoadItemDefinitionsFromCache(t *testing.T) {
+	isolateItemDefinitions(t)
+	datPath, idxPath := writeItemCache(t,
+		// coins: name, stackable, value 1
+		[]byte{2, 'C', 'o', 'i', 'n', 's', 10, 11, 12, 0, 0, 0, 1, 0},
+		// logs: model, name, examine, members, value 4, an unused note link
+		[]byte{1, 0x04, 0xd2, 2, 'L', 'o', 'g', 's', 10, 3, 'W', 'o', 'o', 'd', 10, 16, 12, 0, 0, 0, 4, 97, 0, 9, 0},
+		// the note of logs, drawn from the note template
+		[]byte{97, 0, 1, 98, 0x03, 0x1f, 0},
+	)
+	if err := LoadItemDefinitionsFromCache(datPath, idxPath); err != nil {
+		t.Fatal(err)
+	}
+	expected := []ItemDefinition{
+		{ID: 0, Name: "Coins", Stackable: true, Value: 1, NoteID: -1},
+		{ID: 1, Name: "Logs", Examine: "Wood", Members: true, Value: 4, NoteID: 2},
+		{ID: 2, Name: "Logs", Examine: NOTE_EXAMINE, Stackable: true, Members: true, Value: 4, Noted: true, NoteID: 1},
+	}
+	if count := ItemDefinitionCount(); count != len(expected) {
+		t.Errorf("loaded %d definitions, want %d", count, len(expected))
+	}
+	for _, want := range expected {
+		if def := ItemDefinitionFor(want.ID); def == nil || *def != want {
+			t.Errorf("item %d loaded as %+v, want %+v", want.ID, def, want)
+		}
+	}
+	if err := ValidateItemDefinitions(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadItemDefinitionsFromCacheInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  [][]byte
+		expected error
+	}{
+		{"unknown opcode", [][]byte{{2, 'A', 10, 0}, {11, 200, 0}},
+			InvalidItemCacheError{1, 200}},
+		{"note of unknown item", [][]byte{{97, 0, 5, 98, 0x03, 0x1f, 0}},
+			InvalidItemDefinitionError{0, "note of unknown item 5"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isolateItemDefinitions(t)
+			datPath, idxPath := writeItemCache(t, test.entries...)
+			if err := LoadItemDefinitionsFromCache(datPath, idxPath); err != test.expected {
+				t.Errorf("got %v, want %v", err, test.expected)
+			}
+			if count := ItemDefinitionCount(); count != 0 {
+				t.Errorf("registered %d definitions from a broken cache", count)
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		isolateItemDefinitions(t)
+		datPath, idxPath := writeItemCache(t, []byte{2, 'A', 10, 0})
+		os.WriteFile(datPath, []byte{0, 1, 2}, 0o644)
+		expected := InvalidItemDefinitionError{0, "entry runs past the end of obj.dat"}
+		if err := LoadItemDefinitionsFromCache(datPath, idxPath); err != expected {
+			t.Errorf("got %v, want %v", err, expected)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := LoadItemDefinitionsFromCache(filepath.Join(dir, "obj.dat"), filepath.Join(dir, "obj.idx")); !os.IsNotExist(err) {
+			t.Errorf("got %v", err)
+		}
+	})
+}
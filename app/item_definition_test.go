@@ -0,0 +1,149 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateItemDefinitions gives the test an empty set of definitions and puts
+// the previous ones back when it ends.
+func isolateItemDefinitions(t *testing.T) {
+	saved := itemDefinitions
+	itemDefinitions = make(map[int]*ItemDefinition)
+	t.Cleanup(func() { itemDefinitions = saved })
+}
+
+func TestLoadItemDefinitions(t *testing.T) {
+	isolateItemDefinitions(t)
+	if err := LoadItemDefinitions("../data/items.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateItemDefinitions(); err != nil {
+		t.Error(err)
+	}
+	if count := ItemDefinitionCount(); count != 48 {
+		t.Errorf("loaded %d definitions, want 25 items and their 23 notes", count)
+	}
+
+	whip := ItemDefinitionFor(4151)
+	if whip == nil || whip.Name != "Abyssal whip" || !whip.Members || whip.Value != 120001 || whip.NoteID != 4152 {
+		t.Fatalf("abyssal whip loaded as %+v", whip)
+	}
+	equipment := whip.Equipment
+	if equipment.Slot != EQUIPMENT_WEAPON || equipment.Requirements[ATTACK] != 70 ||
+		equipment.Bonuses[BONUS_SLASH_ATTACK] != 82 || equipment.Bonuses[BONUS_STRENGTH] != 82 {
+		t.Errorf("abyssal whip equipment %+v", equipment)
+	}
+	if animations := equipment.Animations; animations == nil || animations.Walk != 1660 || animations.Run != 1661 ||
+		animations.Stand != DefaultMovementAnimations.Stand {
+		t.Errorf("abyssal whip animations %+v", animations)
+	}
+
+	note := ItemDefinitionFor(4152)
+	expected := ItemDefinition{ID: 4152, Name: "Abyssal whip", Examine: NOTE_EXAMINE, Stackable: true, Members: true,
+		Value: 120001, Noted: true, NoteID: 4151}
+	if note == nil || *note != expected {
+		t.Errorf("abyssal whip note %+v, want %+v", note, expected)
+	}
+	if coins := ItemDefinitionFor(995); coins == nil || !coins.Stackable || coins.NoteID != -1 || coins.Equipment != nil {
+		t.Errorf("coins loaded as %+v", coins)
+	}
+}
+
+func TestItemDefinitionLookup(t *testing.T) {
+	isolateItemDefinitions(t)
+	if err := LoadItemDefinitions("../data/items.json"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		id             int
+		stackable      bool
+		noted, unnoted int
+	}{
+		{"item", 385, false, 386, 385},
+		{"note", 386, true, 386, 385},
+		{"without a note", 995, true, 995, 995},
+		{"missing", 9999, false, 9999, 9999},
+		{"negative", -1, false, -1, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if IsStackable(test.id) != test.stackable {
+				t.Errorf("stackable %v, want %v", IsStackable(test.id), test.stackable)
+			}
+			if NotedID(test.id) != test.noted || UnnotedID(test.id) != test.unnoted {
+				t.Errorf("noted %d and unnoted %d, want %d and %d", NotedID(test.id), UnnotedID(test.id), test.noted, test.unnoted)
+			}
+		})
+	}
+	if def := ItemDefinitionFor(9999); def != nil {
+		t.Errorf("missing item has definition %+v", def)
+	}
+	if def := ItemDefinitionByName("rune SCIMITAR"); def == nil || def.ID != 1333 {
+		t.Errorf("rune scimitar by name is %+v, want the item rather than its note", def)
+	}
+	if def := ItemDefinitionByName("Dragon scimitar"); def != nil {
+		t.Errorf("unknown name found %+v", def)
+	}
+}
+
+func TestLoadItemDefinitionsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected error
+	}{
+		{"unknown slot", `[{"id": 1, "equipment": {"slot": "tail"}}]`,
+			InvalidItemDefinitionError{1, `unknown equipment slot "tail"`}},
+		{"short bonuses", `[{"id": 2, "equipment": {"slot": "hat", "bonuses": [1, 2]}}]`,
+			InvalidItemDefinitionError{2, "2 bonuses instead of 12"}},
+		{"two-handed shield", `[{"id": 3, "equipment": {"slot": "shield", "twoHanded": true}}]`,
+			InvalidItemDefinitionError{3, "two-handed item that isn't a weapon"}},
+		{"animated hat", `[{"id": 4, "equipment": {"slot": "hat", "animations": {"walk": 1}}}]`,
+			InvalidItemDefinitionError{4, "animations on an item that isn't a weapon"}},
+		{"unknown animation", `[{"id": 5, "equipment": {"slot": "weapon", "animations": {"dance": 1}}}]`,
+			InvalidItemDefinitionError{5, `unknown animation "dance"`}},
+		{"unknown skill", `[{"id": 6, "equipment": {"slot": "weapon", "requirements": {"sailing": 1}}}]`,
+			InvalidItemDefinitionError{6, `requirement of unknown skill "sailing"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isolateItemDefinitions(t)
+			path := filepath.Join(t.TempDir(), "items.json")
+			if err := os.WriteFile(path, []byte(test.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := LoadItemDefinitions(path); err != test.expected {
+				t.Errorf("got %v, want %v", err, test.expected)
+			}
+		})
+	}
+
+	isolateItemDefinitions(t)
+	if err := LoadItemDefinitions(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("loading a missing file got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "items.json")
+	os.WriteFile(path, []byte(`{"id": 1}`), 0o644)
+	if err := LoadItemDefinitions(path); err == nil {
+		t.Error("loaded a file that isn't a list")
+	}
+}
+
+func TestValidateItemDefinitions(t *testing.T) {
+	isolateItemDefinitions(t)
+	RegisterItemDefinition(&ItemDefinition{ID: 1, Name: "Logs", NoteID: 2})
+	RegisterItemDefinition(&ItemDefinition{ID: 3, NoteID: -1})
+	RegisterItemDefinition(&ItemDefinition{ID: 4, Name: "Orphan", Noted: true, NoteID: -1})
+	if err := ValidateItemDefinitions(); err == nil {
+		t.Fatal("accepted a missing note, a missing name and a note without an item")
+	}
+	RegisterItemDefinition(&ItemDefinition{ID: 2, Name: "Logs", Noted: true, NoteID: 1})
+	RegisterItemDefinition(&ItemDefinition{ID: 3, Name: "Ashes", NoteID: -1})
+	delete(itemDefinitions, 4)
+	if err := ValidateItemDefinitions(); err != nil {
+		t.Error(err)
+	}
+}
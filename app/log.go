@@ -0,0 +1,47 @@
+package app
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"rs-go-server/logging"
+)
+
+// loggers of the game's subsystems, their levels are set with the logging
+// package by name
+var (
+	netLog    = logging.For("net")
+	loginLog  = logging.For("login")
+	worldLog  = logging.For("world")
+	packetLog = logging.For("packets")
+)
+
+// logger returns base with the attributes identifying p attached.
+func (p *Player) logger(base *slog.Logger) *slog.Logger {
+	return base.With(slog.Group("player",
+		slog.Int("index", p.Index),
+		slog.String("username", p.Username),
+		slog.String("address", p.Address()),
+	))
+}
+
+// SetPacketTrace turns logging of every packet the player sends on or off.
+// Traced packets are logged at info level, so tracing a single player works
+// without lowering the level of the packets logger for everyone.
+func (p *Player) SetPacketTrace(trace bool) {
+	p.packetTrace.Store(trace)
+}
+
+func (p *Player) PacketTrace() bool {
+	return p.packetTrace.Load()
+}
+
+func (p *Player) tracePacket(packet *Packet) {
+	if !p.PacketTrace() {
+		return
+	}
+	p.logger(packetLog).Info("packet",
+		slog.Int("opcode", int(packet.ID)),
+		slog.Int("length", int(packet.Length)),
+		slog.String("data", hex.EncodeToString(packet.Data.Buffer())),
+	)
+}
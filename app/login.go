@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"rs-go-server/io"
+	"rs-go-server/repo"
+)
+
+type LoginResponse int
+
+const (
+	LOGIN_OK                  LoginResponse = 2
+	LOGIN_INVALID_CREDENTIALS LoginResponse = 3
+	LOGIN_ACCOUNT_DISABLED    LoginResponse = 4
+	LOGIN_ALREADY_LOGGED_IN   LoginResponse = 5
+	LOGIN_GAME_UPDATED        LoginResponse = 6
+	LOGIN_WORLD_FULL          LoginResponse = 7
+	LOGIN_SERVER_OFFLINE      LoginResponse = 8
+	LOGIN_LIMIT_EXCEEDED      LoginResponse = 9
+	LOGIN_BAD_SESSION         LoginResponse = 10
+	LOGIN_MEMBERS_ONLY        LoginResponse = 12
+	LOGIN_COULD_NOT_COMPLETE  LoginResponse = 13
+	LOGIN_UPDATE_IN_PROGRESS  LoginResponse = 14
+	LOGIN_RECONNECT_OK        LoginResponse = 15
+)
+
+type LoginRejectedError struct{ Response LoginResponse }
+
+func (e LoginRejectedError) Error() string {
+	return fmt.Sprintf("client: Login rejected.  Response: %d", e.Response)
+}
+
+// LoginRequest holds the details decoded from the login block.  Validators
+// may set Rights, which is sent to the client on success.  Record is the
+// saved character, nil for a new one.  Replaces is the player a reconnecting
+// client takes over.
+type LoginRequest struct {
+	Username     string
+	Password     []byte
+	Address      string
+	Reconnecting bool
+	Rights       int
+	Record       *repo.PlayerRecord
+	Replaces     *Player
+}
+
+// LoginValidator inspects a login request and returns LOGIN_OK to let it
+// continue down the pipeline, or the response code to reject it with.
+type LoginValidator func(w *World, request *LoginRequest) LoginResponse
+
+// RejectDuplicateLogin refuses a username that is already in the world,
+// unless the client is reconnecting after losing its connection, in which
+// case it takes over the player left behind.
+func RejectDuplicateLogin(w *World, request *LoginRequest) LoginResponse {
+	existing := w.PlayerByUsername(request.Username)
+	switch {
+	case existing == nil:
+	case request.Reconnecting:
+		request.Replaces = existing
+	default:
+		return LOGIN_ALREADY_LOGGED_IN
+	}
+	return LOGIN_OK
+}
+
+// RejectDisabledAccount refuses usernames whose account has been disabled.
+func RejectDisabledAccount(w *World, request *LoginRequest) LoginResponse {
+	if w.Accounts == nil {
+		return LOGIN_OK
+	}
+	account, err := w.Accounts.LoadAccount(io.NameToLong(request.Username))
+	if err == nil && account.Disabled {
+		return LOGIN_ACCOUNT_DISABLED
+	}
+	return LOGIN_OK
+}
+
+// LimitConnectionsPerAddress refuses logins from an address that already has
+// max players in the world.
+func LimitConnectionsPerAddress(max int) LoginValidator {
+	return func(w *World, request *LoginRequest) LoginResponse {
+		count := 0
+		for _, p := range w.Players() {
+			if p.Address() == request.Address && p != request.Replaces {
+				count++
+			}
+		}
+		if count >= max {
+			return LOGIN_LIMIT_EXCEEDED
+		}
+		return LOGIN_OK
+	}
+}
+
+// RejectInvalidUsername refuses usernames the client should never send.
+func RejectInvalidUsername(w *World, request *LoginRequest) LoginResponse {
+	if !ValidUsername(request.Username) {
+		return LOGIN_INVALID_CREDENTIALS
+	}
+	return LOGIN_OK
+}
+
+// ValidUsername reports whether username is one the client lets a player
+// type: up to 12 letters, digits, spaces and underscores, not all of them
+// blank.
+func ValidUsername(username string) bool {
+	if len(username) > io.MaxNameLength || io.NameToLong(username) == 0 {
+		return false
+	}
+	for i := 0; i < len(username); i++ {
+		c := username[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ' ' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validate runs the validation pipeline ahead of authentication.
+func (w *World) validate(request *LoginRequest) LoginResponse {
+	w.loginMutex.Lock()
+	defer w.loginMutex.Unlock()
+	return w.runValidators(request)
+}
+
+func (w *World) runValidators(request *LoginRequest) LoginResponse {
+	for _, validator := range w.LoginValidators {
+		if response := validator(w, request); response != LOGIN_OK {
+			return response
+		}
+	}
+	return LOGIN_OK
+}
+
+// login runs the validation pipeline again and registers p on success.  The
+// login mutex makes validation and registration atomic, so two logins racing
+// for the same username can't both pass RejectDuplicateLogin while their
+// passwords were being checked.  A reconnect is only answered with
+// LOGIN_RECONNECT_OK when it takes over a player, otherwise it's a fresh
+// login.
+func (w *World) login(p *Player, request *LoginRequest) LoginResponse {
+	w.loginMutex.Lock()
+	defer w.loginMutex.Unlock()
+	if response := w.runValidators(request); response != LOGIN_OK {
+		return response
+	}
+	if err := w.Register(p); err != nil {
+		return LOGIN_WORLD_FULL
+	}
+	p.Rights = request.Rights
+	p.replaces = request.Replaces
+	if request.Replaces != nil {
+		return LOGIN_RECONNECT_OK
+	}
+	return LOGIN_OK
+}
@@ -0,0 +1,41 @@
+package app
+
+import "testing"
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"bob", true},
+		{"Bob The Cat", true},
+		{"zezima_99", true},
+		{"twelve chars", true},
+		{"thirteen char", false},
+		{"", false},
+		{"_ _", false},
+		{"x/../bob", false},
+		{"bob.json", false},
+		{"bób", false},
+	}
+	for _, test := range tests {
+		if valid := ValidUsername(test.username); valid != test.valid {
+			t.Errorf("ValidUsername(%q) = %v, want %v", test.username, valid, test.valid)
+		}
+	}
+}
+
+func TestPlayerKey(t *testing.T) {
+	tests := []struct{ username, key string }{
+		{"bob", "bob"},
+		{"Bob The Cat", "bob_the_cat"},
+		{"bob_the_cat", "bob_the_cat"},
+		{"Bob ", "bob"},
+		{"x/../bob", "x____bob"},
+	}
+	for _, test := range tests {
+		if key := playerKey(test.username); key != test.key {
+			t.Errorf("playerKey(%q) = %q, want %q", test.username, key, test.key)
+		}
+	}
+}
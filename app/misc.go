@@ -31,14 +31,9 @@ var (
 	}
 )
 
-func HexToInt(data []byte) int {
-	val, n := 0, 1000
-	for i := range data {
-		num := int(data[i]&0xFF) * n
-		val += num
-		if n > 1 {
-			n = n / 1000
-		}
+func boolToInt(b bool) int {
+	if b {
+		return 1
 	}
-	return val
+	return 0
 }
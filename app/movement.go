@@ -0,0 +1,120 @@
+package app
+
+const (
+	NO_DIRECTION = -1
+
+	// upper bound on queued steps, a single walk packet can't legitimately
+	// cover more than the 104x104 loaded map area
+	MaxQueuedSteps = 208
+)
+
+var (
+	DIRECTION_DELTA_X = [...]int{-1, 0, 1, -1, 1, -1, 0, 1}
+	DIRECTION_DELTA_Y = [...]int{1, 1, 1, 0, 0, -1, -1, -1}
+)
+
+// Direction returns the client direction for a single tile step, or
+// NO_DIRECTION if dx and dy don't describe one.
+func Direction(dx, dy int) int {
+	for i := range DIRECTION_DELTA_X {
+		if DIRECTION_DELTA_X[i] == dx && DIRECTION_DELTA_Y[i] == dy {
+			return i
+		}
+	}
+	return NO_DIRECTION
+}
+
+// MovementQueue holds the single tile steps a player has left to walk.
+type MovementQueue struct {
+	steps   []int
+	lastX   int
+	lastY   int
+	Running bool
+}
+
+func NewMovementQueue() *MovementQueue {
+	return &MovementQueue{}
+}
+
+// Reset clears the queue, new steps are interpolated from the given position.
+func (q *MovementQueue) Reset(from *Position) {
+	q.steps = q.steps[:0]
+	q.lastX, q.lastY = from.X, from.Y
+	q.Running = false
+}
+
+// AddWaypoint queues the steps from the last queued tile to x, y, moving
+// diagonally until one axis lines up and then straight.
+func (q *MovementQueue) AddWaypoint(x, y int) {
+	for (q.lastX != x || q.lastY != y) && len(q.steps) < MaxQueuedSteps {
+		dx, dy := sign(x-q.lastX), sign(y-q.lastY)
+		q.steps = append(q.steps, Direction(dx, dy))
+		q.lastX += dx
+		q.lastY += dy
+	}
+}
+
+func (q *MovementQueue) Empty() bool {
+	return len(q.steps) == 0
+}
+
+// next pops the next step, or returns NO_DIRECTION if there is none.
+func (q *MovementQueue) next() int {
+	if len(q.steps) == 0 {
+		return NO_DIRECTION
+	}
+	direction := q.steps[0]
+	q.steps = q.steps[1:]
+	return direction
+}
+
+// processMovement takes this cycle's steps, one when walking or two when
+// running.
+func (p *Player) processMovement() {
+	p.SecondaryDirection = NO_DIRECTION
+	p.PrimaryDirection = p.step()
+	if p.PrimaryDirection != NO_DIRECTION && p.Movement.Running {
+		p.SecondaryDirection = p.step()
+	}
+}
+
+func (p *Player) step() int {
+	direction := p.Movement.next()
+	if direction != NO_DIRECTION {
+		p.Position.X += DIRECTION_DELTA_X[direction]
+		p.Position.Y += DIRECTION_DELTA_Y[direction]
+	}
+	return direction
+}
+
+// Teleport moves the player straight to pos, discarding any queued steps.
+func (p *Player) Teleport(pos Position) {
+	p.Position = &pos
+	p.Movement.Reset(p.Position)
+	p.PrimaryDirection = NO_DIRECTION
+	p.SecondaryDirection = NO_DIRECTION
+	p.Teleporting = true
+}
+
+// checkRegion resends the map region once the player gets within 16 tiles of
+// the edge of the currently loaded 104x104 area.
+func (p *Player) checkRegion() {
+	if p.LastRegion == nil {
+		p.SendMapRegion()
+		return
+	}
+	x, y := p.Position.RelativeX(p.LastRegion), p.Position.RelativeY(p.LastRegion)
+	if x < 16 || x >= 88 || y < 16 || y >= 88 {
+		p.SendMapRegion()
+	}
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
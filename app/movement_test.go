@@ -0,0 +1,60 @@
+package app
+
+import "testing"
+
+func TestMovementQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		waypoints []Position
+		running   bool
+		expected  []Position // position after each cycle, ending on an idle one
+	}{
+		{"straight", []Position{{3225, 3218, 0}}, false,
+			[]Position{{3223, 3218, 0}, {3224, 3218, 0}, {3225, 3218, 0}, {3225, 3218, 0}}},
+		{"diagonal then straight", []Position{{3220, 3221, 0}}, false,
+			[]Position{{3221, 3219, 0}, {3220, 3220, 0}, {3220, 3221, 0}, {3220, 3221, 0}}},
+		{"through waypoints", []Position{{3222, 3220, 0}, {3224, 3220, 0}}, false,
+			[]Position{{3222, 3219, 0}, {3222, 3220, 0}, {3223, 3220, 0}, {3224, 3220, 0}, {3224, 3220, 0}}},
+		{"running", []Position{{3227, 3213, 0}}, true,
+			[]Position{{3224, 3216, 0}, {3226, 3214, 0}, {3227, 3213, 0}, {3227, 3213, 0}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &Player{Position: &Position{3222, 3218, 0}, Movement: NewMovementQueue()}
+			p.Movement.Reset(p.Position)
+			p.Movement.Running = test.running
+			for _, waypoint := range test.waypoints {
+				p.Movement.AddWaypoint(waypoint.X, waypoint.Y)
+			}
+			for cycle, expected := range test.expected {
+				p.processMovement()
+				if *p.Position != expected {
+					t.Fatalf("at %v after cycle %d, want %v", *p.Position, cycle, expected)
+				}
+			}
+			if !p.Movement.Empty() || p.PrimaryDirection != NO_DIRECTION || p.SecondaryDirection != NO_DIRECTION {
+				t.Errorf("still moving at the end of the path")
+			}
+		})
+	}
+}
+
+func TestMovementQueueLimit(t *testing.T) {
+	q := NewMovementQueue()
+	q.Reset(&Position{3222, 3218, 0})
+	q.AddWaypoint(3222+MaxQueuedSteps+50, 3218)
+	if len(q.steps) != MaxQueuedSteps {
+		t.Errorf("queued %d steps, want %d", len(q.steps), MaxQueuedSteps)
+	}
+}
+
+func TestTeleportClearsQueue(t *testing.T) {
+	p := &Player{Position: &Position{3222, 3218, 0}, Movement: NewMovementQueue()}
+	p.Movement.Reset(p.Position)
+	p.Movement.AddWaypoint(3230, 3218)
+	p.Teleport(Position{3093, 3493, 0})
+	p.processMovement()
+	if *p.Position != (Position{3093, 3493, 0}) || p.PrimaryDirection != NO_DIRECTION {
+		t.Errorf("moved to %v after teleporting", *p.Position)
+	}
+}
@@ -1,9 +1,74 @@
 package app
 
-import "rs-go-server/io"
+import (
+	"encoding/hex"
+	"fmt"
+	"rs-go-server/io"
+	"sync"
+)
+
+const VARIABLE_SIZE = 0xFF
 
 type Packet struct {
-	ID byte
+	ID     byte
 	Length byte
-	Data *io.ByteBuffer
-}
\ No newline at end of file
+	Data   *io.ByteBuffer
+}
+
+// PacketHandler decodes and acts upon a single incoming packet.  A non-nil
+// error disconnects the player.
+type PacketHandler func(p *Player, packet *Packet) error
+
+type PacketSizeMismatchError struct {
+	ID             byte
+	Size, Expected int
+}
+
+func (e PacketSizeMismatchError) Error() string {
+	return fmt.Sprintf("app/packet: handler size for opcode %d does not match PACKET_SIZES.  Size: %d, Expected: %d", e.ID, e.Size, e.Expected)
+}
+
+var (
+	packetHandlers [256]PacketHandler
+
+	unhandledMutex   sync.Mutex
+	unhandledPackets = make(map[byte]bool)
+)
+
+// RegisterPacketHandler binds handler to the given opcode.  size is the
+// payload size the handler expects (VARIABLE_SIZE for variable length
+// packets) and must agree with PACKET_SIZES, otherwise this panics.
+func RegisterPacketHandler(id byte, size int, handler PacketHandler) {
+	if expected := int(PACKET_SIZES[id]); expected != size {
+		panic(PacketSizeMismatchError{ID: id, Size: size, Expected: expected})
+	}
+	if packetHandlers[id] != nil {
+		panic(fmt.Sprintf("app/packet: handler for opcode %d registered twice", id))
+	}
+	packetHandlers[id] = handler
+}
+
+func dispatchPacket(p *Player, packet *Packet) error {
+	handler := packetHandlers[packet.ID]
+	if handler == nil {
+		logUnhandledPacket(p, packet)
+		return nil
+	}
+	return handler(p, packet)
+}
+
+// logUnhandledPacket dumps the contents of an unknown packet, only the first
+// time each opcode is seen.
+func logUnhandledPacket(p *Player, packet *Packet) {
+	unhandledMutex.Lock()
+	defer unhandledMutex.Unlock()
+	if unhandledPackets[packet.ID] {
+		return
+	}
+	unhandledPackets[packet.ID] = true
+	p.logger(packetLog).Warn("unhandled packet",
+		"opcode", packet.ID,
+		"length", packet.Length,
+		"data", hex.EncodeToString(packet.Data.Buffer()),
+	)
+}
@@ -1,18 +1,29 @@
 package app
 
-import (
-	"fmt"
-	"rs-go-server/io"
-)
+import "rs-go-server/io"
 
-func HandleButtonPacket(p *Player, packet *Packet) {
+const BUTTON_LOGOUT = 9154
+
+func init() {
+	RegisterPacketHandler(185, 2, HandleButtonPacket)
+}
+
+func HandleButtonPacket(p *Player, packet *Packet) error {
 	buf := io.NewInBuffer(packet.Data)
 
-	buttonBytes := buf.ReadBytes(2, io.STANDARD)
-	button := HexToInt(buttonBytes)
-	fmt.Printf("Button: %v\n", button)
+	button := int(buf.ReadShort(io.STANDARD, io.BIG))
+	p.logger(packetLog).Debug("button", "id", button)
 	switch button {
-	case 9154:
+	case BUTTON_LOGOUT:
 		p.SendLogout()
+	case BUTTON_BANK_WITHDRAW_ITEM:
+		p.SetBankNoteMode(false)
+	case BUTTON_BANK_WITHDRAW_NOTE:
+		p.SetBankNoteMode(true)
+	case BUTTON_BANK_SWAP:
+		p.SetBankInsertMode(false)
+	case BUTTON_BANK_INSERT:
+		p.SetBankInsertMode(true)
 	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package app
+
+import (
+	rsio "rs-go-server/io"
+	"testing"
+)
+
+// buttonPacket is the 185 frame the client sends when button is clicked.
+func buttonPacket(button int) *Packet {
+	data := rsio.NewByteBufferWithBytes([]byte{byte(button >> 8), byte(button)})
+	data.Flip()
+	return &Packet{185, 2, data}
+}
+
+func TestButtonPacket(t *testing.T) {
+	tests := []struct {
+		name                   string
+		button                 int
+		startNote, startInsert bool
+		noteMode, insertMode   bool
+		expected               []byte
+	}{
+		{"logout", BUTTON_LOGOUT, false, false, false, false, []byte{109}},
+		{"withdraw notes", BUTTON_BANK_WITHDRAW_NOTE, false, false, true, false, []byte{36, 0x73, 0x00, 1}},
+		{"withdraw items", BUTTON_BANK_WITHDRAW_ITEM, true, false, false, false, []byte{36, 0x73, 0x00, 0}},
+		{"insert", BUTTON_BANK_INSERT, false, false, false, true, []byte{36, 0x30, 0x01, 1}},
+		{"swap", BUTTON_BANK_SWAP, false, true, false, false, []byte{36, 0x30, 0x01, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, client := newTestPlayer(t)
+			p.BankNoteMode, p.BankInsertMode = test.startNote, test.startInsert
+			if err := dispatchPacket(p, buttonPacket(test.button)); err != nil {
+				t.Fatal(err)
+			}
+			if p.BankNoteMode != test.noteMode || p.BankInsertMode != test.insertMode {
+				t.Errorf("note mode %v and insert mode %v, want %v and %v", p.BankNoteMode, p.BankInsertMode, test.noteMode, test.insertMode)
+			}
+			expectSent(t, p, client, test.expected)
+		})
+	}
+}
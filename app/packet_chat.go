@@ -0,0 +1,43 @@
+package app
+
+import (
+	"rs-go-server/io"
+	"strings"
+)
+
+const (
+	MaxChatColor   = 11
+	MaxChatEffects = 5
+)
+
+func init() {
+	RegisterPacketHandler(4, VARIABLE_SIZE, HandleChatPacket)
+}
+
+// HandleChatPacket decodes public chat and shows it to every player that has
+// the speaker as a local player, through the chat update block.
+func HandleChatPacket(p *Player, packet *Packet) error {
+	if packet.Length < 2 {
+		return UnexpectedPacketSizeError{Received: int(packet.Length), Expected: 2}
+	}
+	buf := io.NewInBuffer(packet.Data)
+
+	effects := int(buf.ReadByte(io.S))
+	color := int(buf.ReadByte(io.S))
+	packed := buf.ReadBytesReverse(int(packet.Length)-2, io.A)
+	if effects > MaxChatEffects || color > MaxChatColor {
+		return nil
+	}
+
+	text := []rune(io.UnpackText(packed))
+	if len(text) > io.MaxTextLength {
+		text = text[:io.MaxTextLength]
+	}
+	message := strings.TrimSpace(string(text))
+	if message == "" {
+		return nil
+	}
+	p.logger(worldLog).Info("chat", "message", io.FormatText(message))
+	p.Chat(&ChatMessage{Effects: effects, Color: color, Text: io.PackText(message)})
+	return nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"fmt"
+	"rs-go-server/io"
+)
+
+func init() {
+	RegisterPacketHandler(101, 13, HandleDesignPacket)
+}
+
+// HandleDesignPacket applies the looks chosen on the character design screen.
+func HandleDesignPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+
+	var appearance Appearance
+	appearance.Gender = int(buf.ReadByte(io.STANDARD))
+	for i := range appearance.Kits {
+		appearance.Kits[i] = int(int8(buf.ReadByte(io.STANDARD)))
+	}
+	for i := range appearance.Colors {
+		appearance.Colors[i] = int(buf.ReadByte(io.STANDARD))
+	}
+	if !appearance.Valid() {
+		p.logger(packetLog).Warn("invalid appearance", "appearance", fmt.Sprintf("%+v", appearance))
+		return nil
+	}
+	p.SetAppearance(appearance)
+	return nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import "rs-go-server/io"
+
+// The client has five packets for the options of an item on an interface,
+// what each option does depends on the interface the item is on.
+
+func init() {
+	RegisterPacketHandler(145, 6, HandleItemOption1Packet)
+	RegisterPacketHandler(117, 6, HandleItemOption2Packet)
+	RegisterPacketHandler(43, 6, HandleItemOption3Packet)
+	RegisterPacketHandler(129, 6, HandleItemOption4Packet)
+	RegisterPacketHandler(135, 6, HandleItemOption5Packet)
+	RegisterPacketHandler(214, 7, HandleMoveItemPacket)
+}
+
+func HandleItemOption1Packet(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	interfaceID := int(buf.ReadShort(io.A, io.BIG))
+	slot := int(buf.ReadShort(io.A, io.BIG))
+	id := int(buf.ReadShort(io.A, io.BIG))
+	p.itemOption(1, interfaceID, slot, id)
+	return nil
+}
+
+func HandleItemOption2Packet(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	interfaceID := int(buf.ReadShort(io.A, io.LITTLE))
+	id := int(buf.ReadShort(io.A, io.LITTLE))
+	slot := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	p.itemOption(2, interfaceID, slot, id)
+	return nil
+}
+
+func HandleItemOption3Packet(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	interfaceID := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	id := int(buf.ReadShort(io.A, io.BIG))
+	slot := int(buf.ReadShort(io.A, io.BIG))
+	p.itemOption(3, interfaceID, slot, id)
+	return nil
+}
+
+func HandleItemOption4Packet(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	slot := int(buf.ReadShort(io.A, io.BIG))
+	interfaceID := int(buf.ReadShort(io.STANDARD, io.BIG))
+	id := int(buf.ReadShort(io.A, io.BIG))
+	p.itemOption(4, interfaceID, slot, id)
+	return nil
+}
+
+func HandleItemOption5Packet(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	slot := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	interfaceID := int(buf.ReadShort(io.A, io.BIG))
+	id := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	p.itemOption(5, interfaceID, slot, id)
+	return nil
+}
+
+func (p *Player) itemOption(option, interfaceID, slot, id int) {
+	switch interfaceID {
+	case INTERFACE_EQUIPMENT:
+		if option == 1 && p.Equipment.Get(slot).ID == id {
+			p.Unequip(slot)
+		}
+	case INTERFACE_BANK_ITEMS:
+		p.bankItemOption(option, func(amount int) {
+			p.Withdraw(slot, id, amount)
+		})
+	case INTERFACE_BANK_INVENTORY:
+		p.bankItemOption(option, func(amount int) {
+			p.Deposit(slot, id, amount)
+		})
+	}
+}
+
+// HandleMoveItemPacket is an item dragged onto another slot of the same
+// interface.
+func HandleMoveItemPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+	interfaceID := int(buf.ReadShort(io.A, io.LITTLE))
+	buf.ReadByte(io.C) // the client's idea of insert mode, ours is kept server side
+	from := int(buf.ReadShort(io.A, io.LITTLE))
+	to := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	switch interfaceID {
+	case INTERFACE_INVENTORY, INTERFACE_BANK_INVENTORY:
+		p.Inventory.Swap(from, to)
+	case INTERFACE_BANK_ITEMS:
+		p.MoveBankItem(from, to)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import "rs-go-server/io"
+
+func init() {
+	RegisterPacketHandler(164, VARIABLE_SIZE, HandleWalkPacket) // regular walk
+	RegisterPacketHandler(248, VARIABLE_SIZE, HandleWalkPacket) // minimap walk
+	RegisterPacketHandler(98, VARIABLE_SIZE, HandleWalkPacket)  // walk on command
+}
+
+func HandleWalkPacket(p *Player, packet *Packet) error {
+	buf := io.NewInBuffer(packet.Data)
+
+	length := int(packet.Length)
+	if packet.ID == 248 {
+		length -= 14 // trailing anti-cheat data
+	}
+	if length < 5 || (length-5)%2 != 0 {
+		return UnexpectedPacketSizeError{Received: length, Expected: 5}
+	}
+
+	steps := (length - 5) / 2
+	firstX := int(buf.ReadShort(io.A, io.LITTLE))
+	deltas := buf.ReadBytes(steps*2, io.STANDARD)
+	firstY := int(buf.ReadShort(io.STANDARD, io.LITTLE))
+	running := buf.ReadByte(io.C) == 1
+
+	if p.openInterface != 0 {
+		p.CloseInterfaces()
+	}
+	p.Movement.Reset(p.Position)
+	p.Movement.Running = running
+	p.Movement.AddWaypoint(firstX, firstY)
+	for i := 0; i < steps; i++ {
+		p.Movement.AddWaypoint(firstX+int(int8(deltas[i*2])), firstY+int(int8(deltas[i*2+1])))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	rsio "rs-go-server/io"
+	"slices"
+	"testing"
+)
+
+// walkPacket frames data as the client would send it under opcode.
+func walkPacket(opcode byte, data ...byte) *Packet {
+	buffer := rsio.NewByteBufferWithBytes(data)
+	buffer.Flip()
+	return &Packet{opcode, byte(len(data)), buffer}
+}
+
+func TestWalkPacket(t *testing.T) {
+	// a click on (3225, 3218) followed by a waypoint 2 east and 3 north of it
+	path := []byte{
+		0x19, 0x0c, // first x 3225, little endian with the low byte +128
+		2, 3, // waypoint delta
+		0x92, 0x0c, // first y 3218, little endian
+	}
+	walking := append(slices.Clip(path), 0x00)
+	running := append(slices.Clip(path), 0xff) // 1 negated
+	antiCheat := make([]byte, 14)
+	east, northEast, north := Direction(1, 0), Direction(1, 1), Direction(0, 1)
+	expected := []int{east, east, east, northEast, northEast, north}
+
+	tests := []struct {
+		name    string
+		packet  *Packet
+		running bool
+	}{
+		{"regular", walkPacket(164, walking...), false},
+		{"regular running", walkPacket(164, running...), true},
+		{"minimap", walkPacket(248, append(slices.Clip(walking), antiCheat...)...), false},
+		{"command", walkPacket(98, running...), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, _ := newTestPlayer(t)
+			p.Position = &Position{3222, 3218, 0}
+			if err := dispatchPacket(p, test.packet); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(p.Movement.steps, expected) {
+				t.Errorf("queued steps %v, want %v", p.Movement.steps, expected)
+			}
+			if p.Movement.Running != test.running {
+				t.Errorf("running %v, want %v", p.Movement.Running, test.running)
+			}
+		})
+	}
+}
+
+func TestWalkPacketSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *Packet
+	}{
+		{"too short", walkPacket(164, 0x19, 0x0c, 0x92, 0x0c)},
+		{"half a waypoint", walkPacket(164, 0x19, 0x0c, 2, 0x92, 0x0c, 0x00)},
+		{"minimap without anti-cheat data", walkPacket(248, 0x19, 0x0c, 0x92, 0x0c, 0x00)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, _ := newTestPlayer(t)
+			if err := dispatchPacket(p, test.packet); err == nil {
+				t.Error("accepted a malformed walk packet")
+			}
+			if !p.Movement.Empty() {
+				t.Errorf("queued steps %v from a malformed packet", p.Movement.steps)
+			}
+		})
+	}
+}
@@ -1,11 +1,12 @@
 package app
 
 import (
-	"fmt"
+	"bytes"
 	"net"
 	"rs-go-server/io"
 	"rs-go-server/repo"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -13,82 +14,131 @@ const (
 	LOGGING_IN = 1
 	LOGGED_IN  = 2
 
-	CycleMillis = 600
+	RIGHTS_PLAYER        = 0
+	RIGHTS_MODERATOR     = 1
+	RIGHTS_ADMINISTRATOR = 2
+
+	INVENTORY_SIZE = 28
+
+	// maximum number of decoded packets waiting for the next cycle
+	PacketQueueSize = 64
 )
 
 type Player struct {
-	ID             int
-	Socket         *net.TCPConn
-	DisconnectFunc func()
-	TimeoutTimer   *Timer
-	LoginStage     int
-	UpdateRequired bool
-	Connected      bool
-	Username       string
-	Password       []byte
-	inBuffer       *io.ByteBuffer
-	Encryptor      repo.Cipher
-	Decryptor      repo.Cipher
-	Position       *Position
-	Inventory      ItemContainer
-	PacketID       byte
-	PacketLength   byte
+	Index              int
+	Socket             *net.TCPConn
+	TimeoutTimer       *Timer
+	LoginStage         int
+	UpdateFlags        int
+	Teleporting        bool
+	RegionChanged      bool
+	Connected          bool
+	Username           string
+	Rights             int
+	Password           []byte
+	inBuffer           *io.ByteBuffer
+	readBuffer         []byte // reused by every read from the socket
+	Encryptor          repo.Cipher
+	Decryptor          repo.Cipher
+	Position           *Position
+	LastRegion         *Position
+	Movement           *MovementQueue
+	PrimaryDirection   int
+	SecondaryDirection int
+	LocalPlayers       []*Player
+	animation          Animation
+	graphic            Graphic
+	forcedChat         string
+	chatMessage        *ChatMessage
+	faceEntity         int
+	facePosition       Position
+	hits               [2]Hit
+	forcedMovement     ForcedMovement
+	Inventory          *ItemContainer
+	Equipment          *ItemContainer
+	Bank               *ItemContainer
+	BankNoteMode       bool
+	BankInsertMode     bool
+	Appearance         Appearance
+	Animations         MovementAnimations
+	Skills             *Skills
+	HeadIcon           int
+	Skulled            bool
+	openInterface      int
+	enterAmount        func(amount int)
+	bankInventory      ContainerListener // the bank's inventory panel
+	PacketID           byte
+	PacketLength       byte
+	packets            chan *Packet
+	active             bool
+	replaces           *Player // the player a reconnect takes over
+	world              *World
+	outMutex           sync.Mutex
+	outBuffer          bytes.Buffer
+	packetTrace        atomic.Bool
 }
 
-func NewPlayer(id int, socket *net.TCPConn, disconnectFunc func()) *Player {
+func NewPlayer(socket *net.TCPConn) *Player {
 	player := &Player{
-		ID:             id,
-		Socket:         socket,
-		DisconnectFunc: disconnectFunc,
-		TimeoutTimer:   NewTimer(5 * time.Second),
-		Connected:      true,
-		inBuffer:       io.NewByteBuffer(512),
-		UpdateRequired: true,
-		PacketID:       0xFF,
-		PacketLength:   0xFF,
-	}
-	player.Position = &Position{X: 3222, Y: 3218}
-	player.Inventory = NewItemContainer(28)
-	for _, i := range [...]int{1038, 1040, 1042, 1044, 1046, 1048} {
-		player.Inventory.Add(&Item{i, 1})
+		Socket:       socket,
+		Connected:    true,
+		inBuffer:     io.NewByteBuffer(512),
+		UpdateFlags:  UPDATE_APPEARANCE,
+		Teleporting:  true,
+		PacketID:     0xFF,
+		PacketLength: 0xFF,
+		packets:      make(chan *Packet, PacketQueueSize),
 	}
+	player.Position = &Position{}
+	player.Movement = NewMovementQueue()
+	player.PrimaryDirection = NO_DIRECTION
+	player.SecondaryDirection = NO_DIRECTION
+	player.Inventory = NewItemContainer(INVENTORY_SIZE, STACK_DEFINITION)
+	player.Equipment = NewItemContainer(EQUIPMENT_SIZE, STACK_DEFINITION)
+	player.Inventory.AddListener(NewInterfaceListener(player, INTERFACE_INVENTORY))
+	player.Equipment.AddListener(NewInterfaceListener(player, INTERFACE_EQUIPMENT))
+	player.Bank = NewItemContainer(BANK_SIZE, STACK_ALWAYS)
+	player.Bank.AddListener(NewInterfaceListener(player, INTERFACE_BANK_ITEMS))
+	player.bankInventory = NewInterfaceListener(player, INTERFACE_BANK_INVENTORY)
+	player.Appearance = DefaultAppearance()
+	player.Animations = DefaultMovementAnimations
+	player.Skills = NewSkills()
 	return player
 }
 
-func (p *Player) Process() error {
-	err := p.HandleIncomingData()
-	if err != nil {
-		fmt.Println(err)
-		p.Connected = false
-		p.Socket.Close()
+// Address is the remote IP of the player's connection.
+func (p *Player) Address() string {
+	if addr, ok := p.Socket.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
 	}
-	return err
+	return p.Socket.RemoteAddr().String()
 }
 
 func (p *Player) Update() {
+	p.Inventory.NotifyListeners()
+	p.Equipment.NotifyListeners()
+	p.Bank.NotifyListeners()
 	p.sendUpdate()
-	p.UpdateRequired = false
 }
 
-func (p *Player) Cycle() {
-	for {
-		cycleStart := time.Now()
-		err := p.Process()
-		if err != nil {
-			return
-		}
-		if p.Connected && p.LoginStage == LOGGED_IN {
-			p.Update()
-		}
-		p.TimeoutTimer.Tick()
-		time.Sleep(time.Now().Sub(cycleStart) + CycleMillis*time.Millisecond)
-	}
+// resetUpdateFlags is called once every player has been updated, as other
+// players' updates depend on the same flags.
+func (p *Player) resetUpdateFlags() {
+	p.UpdateFlags = 0
+	p.forcedChat = ""
+	p.chatMessage = nil
+	p.Teleporting = false
+	p.RegionChanged = false
 }
 
 func (p *Player) Login() error {
-	p.SendLoginFrame()
 	p.SendMapRegion()
-	p.SendInventory()
+	p.Inventory.Refresh()
+	p.Equipment.Refresh()
+	p.SendEquipmentBonuses()
+	for skill := range p.Skills {
+		p.SendSkill(skill)
+	}
 	p.SendSidebarInterface(0, 5855)
 	p.SendSidebarInterface(1, 3917)
 	p.SendSidebarInterface(2, 638)
@@ -2,10 +2,12 @@ package app
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"rs-go-server/crypto"
 	"rs-go-server/io"
 	"strings"
+	"time"
 )
 
 // responsible for I/O for player
@@ -22,27 +24,71 @@ func (e InvalidLoginRequestError) Error() string {
 	return fmt.Sprintf("client: Invalid login request.  Request: %d", e.Request)
 }
 
+type PacketQueueFullError struct{ Size int }
+
+func (e PacketQueueFullError) Error() string {
+	return fmt.Sprintf("client: Packet queue full.  Size: %d", e.Size)
+}
+
+var ErrConnectionClosed = errors.New("client: connection closed")
+
+type InvalidSecureBlockError struct{ Opcode byte }
+
+func (e InvalidSecureBlockError) Error() string {
+	return fmt.Sprintf("client: Invalid RSA block opcode.  Opcode: %d", e.Opcode)
+}
+
 type InvalidClientVersionError struct{ Version uint16 }
 
 func (e InvalidClientVersionError) Error() string {
 	return fmt.Sprintf("client: Invalid client version.  Version: %d", e.Version)
 }
 
+// Listen is the reader side of a connection.  It runs on its own goroutine,
+// performs the login handshake and then decodes incoming packets into the
+// player's queue, which the world drains once per cycle.
+func (p *Player) Listen(w *World) {
+	defer close(p.packets)
+	p.world = w
+	p.TimeoutTimer = NewTimer(w.Config.Timeout())
+	for {
+		if err := p.HandleIncomingData(); err != nil {
+			p.logger(netLog).Info("connection closed", "reason", err)
+			p.Socket.Close()
+			return
+		}
+	}
+}
+
 func (p *Player) HandleIncomingData() error {
-	incomingData := make([]byte, 8192)
-	size, err := p.Socket.Read(incomingData)
 	p.inBuffer.Compact()
-	p.inBuffer.Append(incomingData[:size])
-	p.inBuffer.Flip()
-
+	n := p.inBuffer.Len() - p.inBuffer.Position
+	if cap(p.readBuffer) < n {
+		p.readBuffer = make([]byte, n)
+	}
+	incomingData := p.readBuffer[:n]
+	// also drops connections that never finish the login handshake, the
+	// world only times out players that are in it
+	p.Socket.SetReadDeadline(time.Now().Add(p.world.Config.Timeout()))
+	size, err := p.Socket.Read(incomingData)
 	if err != nil {
-		fmt.Printf("Player incoming data error: %v", err)
 		return err
 	}
+	p.TimeoutTimer.Tick()
+	p.inBuffer.Append(incomingData[:size])
+	p.inBuffer.Flip()
+	if p.LoginStage != LOGGED_IN {
+		// the copy in inBuffer is what the login is parsed from, and is
+		// cleared once the password has been read out of it
+		clear(incomingData[:size])
+	}
+
 	buffer := io.NewInBuffer(p.inBuffer)
 
 	if p.LoginStage != LOGGED_IN {
-		return p.handleLogin(buffer)
+		if err := p.handleLogin(buffer); err != nil || p.LoginStage != LOGGED_IN {
+			return err
+		}
 	}
 
 	for p.inBuffer.Remaining() > 0 {
@@ -50,7 +96,6 @@ func (p *Player) HandleIncomingData() error {
 			packetId, _ := p.inBuffer.Read()
 			p.PacketID = packetId
 			p.PacketID -= byte(p.Decryptor.Next())
-			fmt.Printf("Packet ID: %d\n", p.PacketID)
 		}
 
 		if p.PacketLength == 0xFF {
@@ -65,17 +110,43 @@ func (p *Player) HandleIncomingData() error {
 			}
 		}
 
-		if p.inBuffer.Remaining() >= int(p.PacketLength) {
-			data := make([]byte, p.PacketLength)
-			for i := range data {
-				data[i], _ = p.inBuffer.Read()
-			}
-			packet := &Packet{p.PacketID, p.PacketLength, io.NewByteBufferWithBytes(data)}
-			packet.Data.Flip()
-			p.handlePacket(packet)
+		if p.inBuffer.Remaining() < int(p.PacketLength) {
+			break
+		}
+		data := make([]byte, p.PacketLength)
+		for i := range data {
+			data[i], _ = p.inBuffer.Read()
+		}
+		packet := &Packet{p.PacketID, p.PacketLength, io.NewByteBufferWithBytes(data)}
+		packet.Data.Flip()
+		p.tracePacket(packet)
+
+		p.PacketID = 0xFF
+		p.PacketLength = 0xFF
+		select {
+		case p.packets <- packet:
+		default:
+			return PacketQueueFullError{Size: PacketQueueSize}
+		}
+	}
+	return nil
+}
 
-			p.PacketID = 0xFF
-			p.PacketLength = 0xFF
+// processQueuedPackets handles every packet queued since the last cycle,
+// stopping early if one of them gets the player disconnected.  Called from
+// the world loop only.
+func (p *Player) processQueuedPackets() error {
+	for p.active {
+		select {
+		case packet, ok := <-p.packets:
+			if !ok {
+				return ErrConnectionClosed
+			}
+			if err := dispatchPacket(p, packet); err != nil {
+				return err
+			}
+		default:
+			return nil
 		}
 	}
 	return nil
@@ -100,18 +171,18 @@ func (p *Player) handleLogin(buffer *io.StreamBuffer) error {
 		randBytes := make([]byte, 8)
 		rand.Read(randBytes)
 		out.WriteBytes(io.NewByteBufferWithBytes(randBytes))
-		err := p.Send(out)
+		p.Send(out)
 
 		p.LoginStage = LOGGING_IN
-		return err
+		return p.Flush()
 	case LOGGING_IN:
 		if l := buffer.Buffer.Remaining(); l < 2 {
 			return UnexpectedPacketSizeError{Expected: 2, Received: l}
 		}
 
-		request, _ := buffer.Buffer.Read()
-		if request != 16 && request != 18 {
-			return InvalidLoginRequestError{Request: request}
+		opcode, _ := buffer.Buffer.Read()
+		if opcode != 16 && opcode != 18 {
+			return InvalidLoginRequestError{Request: opcode}
 		}
 
 		blockLength, _ := buffer.Buffer.Read()
@@ -131,184 +202,115 @@ func (p *Player) handleLogin(buffer *io.StreamBuffer) error {
 		for i := 0; i < 9; i++ {     // CRC Keys
 			buffer.ReadInt(io.STANDARD, io.BIG)
 		}
-		buffer.ReadByte(io.STANDARD) // RSA block length
-		buffer.ReadByte(io.STANDARD) // RSA opcode
-		buffer.ReadString()          // codebase
+		// the secure block is encrypted when the world has a key and sent as
+		// is otherwise, either way it's parsed the same
+		secureLength := int(buffer.ReadByte(io.STANDARD))
+		if l := buffer.Remaining(); l < secureLength {
+			return UnexpectedPacketSizeError{Expected: secureLength, Received: l}
+		}
+		secure := buffer
+		if key := p.world.RSAKey; key != nil {
+			block := key.Decrypt(buffer.ReadBytes(secureLength, io.STANDARD))
+			secure = io.NewInBuffer(io.NewByteBufferWithBytes(block))
+			secure.Buffer.Flip()
+		}
+		if opcode := secure.ReadByte(io.STANDARD); opcode != 10 {
+			return InvalidSecureBlockError{Opcode: opcode}
+		}
 
-		clientHalf := buffer.ReadLong(io.STANDARD, io.BIG)
-		serverHalf := buffer.ReadLong(io.STANDARD, io.BIG)
+		clientHalf := secure.ReadLong(io.STANDARD, io.BIG)
+		serverHalf := secure.ReadLong(io.STANDARD, io.BIG)
 		isaacSeed := [...]uint32{uint32(clientHalf >> 32), uint32(clientHalf), uint32(serverHalf >> 32), uint32(serverHalf)}
-		//p.Decryptor = crypto.NewISAACCipher(isaacSeed[:])
-		p.Decryptor = crypto.NewMockISAACCipher(isaacSeed[:])
-		for i := range isaacSeed {
-			isaacSeed[i] += 50
+		newCipher := crypto.NewISAACCipher
+		if !p.world.Config.Login.Encryption {
+			newCipher = crypto.NewMockISAACCipher
+		}
+		p.Decryptor = newCipher(isaacSeed[:])
+		p.Encryptor = newCipher(crypto.EncryptorSeed(isaacSeed[:]))
+
+		secure.ReadInt(io.STANDARD, io.BIG) // user ID
+		p.Username = strings.TrimSpace(secure.ReadString())
+		passwordStart := secure.Buffer.Position
+		p.Password = secure.ReadStringBytes()
+		if secure != buffer {
+			clear(secure.Buffer.Buf)
+		} else {
+			// a plaintext block is read straight out of the connection's buffer
+			clear(secure.Buffer.Buf[passwordStart:secure.Buffer.Position])
 		}
-		//p.Encryptor = crypto.NewISAACCipher(isaacSeed[:])
-		p.Encryptor = crypto.NewMockISAACCipher(isaacSeed[:])
-
-		buffer.ReadInt(io.STANDARD, io.BIG) // user ID
-		p.Username = strings.TrimSpace(buffer.ReadString())
-		p.Password = []byte(buffer.ReadString())
 
-		err := p.Login()
-		if err != nil {
+		request := &LoginRequest{
+			Username:     p.Username,
+			Password:     p.Password,
+			Address:      p.Address(),
+			Reconnecting: opcode == 18,
+		}
+		// logins the world would refuse anyway are turned away before the
+		// password is hashed or an account registered for them
+		response := p.world.validate(request)
+		if response == LOGIN_OK {
+			response = p.world.authenticate(request)
+		}
+		clear(p.Password)
+		p.Password, request.Password = nil, nil
+		if response == LOGIN_OK {
+			response = p.loadRecord(request)
+		}
+		if response == LOGIN_OK {
+			response = p.world.login(p, request)
+		}
+		if response != LOGIN_OK && response != LOGIN_RECONNECT_OK {
+			p.logger(loginLog).Info("login rejected", "response", int(response))
+			p.SendLoginResponse(response)
+			p.Flush()
+			return LoginRejectedError{Response: response}
+		}
+		p.SendLoginFrame(response)
+		if err := p.Flush(); err != nil {
+			p.world.Unregister(p)
 			return err
 		}
 		p.LoginStage = LOGGED_IN
+		p.logger(loginLog).Info("logged in", "rights", p.Rights)
+		p.world.queueLogin(p)
 	}
 	return nil
 }
 
-func (p *Player) handlePacket(packet *Packet) {
-	switch packet.ID {
-	case 185: //button clicking
-		HandleButtonPacket(p, packet)
-	}
+func (p *Player) SendLoginResponse(response LoginResponse) error {
+	buffer := io.NewOutBuffer(1)
+	buffer.WriteByte(int(response), io.STANDARD)
+	return p.Send(buffer)
 }
 
-func (p *Player) SendLoginFrame() error {
+// SendLoginFrame accepts the login.  A reconnecting client only expects the
+// response code, a fresh login is followed by the rights and flagged bytes.
+func (p *Player) SendLoginFrame(response LoginResponse) error {
+	if response == LOGIN_RECONNECT_OK {
+		return p.SendLoginResponse(response)
+	}
 	buffer := io.NewOutBuffer(3)
-	buffer.WriteByte(2, io.STANDARD)
-	buffer.WriteByte(0, io.STANDARD)
+	buffer.WriteByte(int(response), io.STANDARD)
+	buffer.WriteByte(p.Rights, io.STANDARD)
 	buffer.WriteByte(0, io.STANDARD)
 	return p.Send(buffer)
 }
 
-func (p *Player) SendMapRegion() error {
-	buffer := io.NewOutBuffer(5)
-	buffer.WriteHeader(p.Encryptor, 69)
-	buffer.WriteShort(p.Position.RegionX()+6, io.A, io.BIG)
-	buffer.WriteShort(p.Position.RegionY()+6, io.STANDARD, io.BIG)
-	return p.Send(buffer)
-}
-
-func (p *Player) sendUpdate() error {
-	out := io.NewOutBuffer(4096)
-	block := io.NewOutBuffer(3072)
-
-	out.WriteVariableShortPacketHeader(p.Encryptor, 81)
-	out.SetAccessType(io.BIT_ACCESS)
-
-	p.updateLocalPlayerMovement(out)
-	if p.UpdateRequired {
-		p.updateState(block)
-	}
-
-	out.WriteBits(8, 0)
-
-	if block.Buffer.Position > 0 {
-		out.WriteBits(11, 2047)
-		out.SetAccessType(io.BYTE_ACCESS)
-		out.WriteBytes(block.Buffer)
-	} else {
-		out.SetAccessType(io.BYTE_ACCESS)
-	}
-
-	out.FinishVariableShortPacketHeader()
-	return p.Send(out)
-}
-
-func (p *Player) updateLocalPlayerMovement(buf *io.StreamBuffer) {
-	if p.UpdateRequired {
-		buf.WriteBit(true)
-		buf.WriteBits(2, 3)
-		buf.WriteBits(2, p.Position.Z)
-		buf.WriteBit(true)
-		buf.WriteBit(true)
-		buf.WriteBits(7, p.Position.LocalY())
-		buf.WriteBits(7, p.Position.LocalX())
-	} else {
-		buf.WriteBit(false)
-	}
-}
-
-func (p *Player) updateState(buf *io.StreamBuffer) {
-	const mask = 0x10
-	buf.WriteByte(mask, io.STANDARD)
-	p.appendAppearance(buf)
-}
-
-func (p *Player) appendAppearance(buf *io.StreamBuffer) {
-	block := io.NewOutBuffer(128)
-	block.WriteByte(0, io.STANDARD)
-	block.WriteByte(0, io.STANDARD)
-
-	// equipment
-	block.WriteByte(0, io.STANDARD)
-	block.WriteByte(0, io.STANDARD)
-	block.WriteByte(0, io.STANDARD)
-	block.WriteByte(0, io.STANDARD)
-	block.WriteShort(0x100+18, io.STANDARD, io.BIG)
-	block.WriteByte(0, io.STANDARD)
-	block.WriteShort(0x100+26, io.STANDARD, io.BIG)
-	block.WriteShort(0x100+36, io.STANDARD, io.BIG)
-	block.WriteShort(0x100, io.STANDARD, io.BIG)
-	block.WriteShort(0x100+33, io.STANDARD, io.BIG)
-	block.WriteShort(0x100+42, io.STANDARD, io.BIG)
-	block.WriteShort(0x100+10, io.STANDARD, io.BIG)
-
-	// colors
-	block.WriteByte(7, io.STANDARD)
-	block.WriteByte(8, io.STANDARD)
-	block.WriteByte(9, io.STANDARD)
-	block.WriteByte(5, io.STANDARD)
-	block.WriteByte(0, io.STANDARD)
-
-	// animations
-	block.WriteShort(808, io.STANDARD, io.BIG)
-	block.WriteShort(0x337, io.STANDARD, io.BIG)
-	block.WriteShort(819, io.STANDARD, io.BIG)
-	block.WriteShort(0x334, io.STANDARD, io.BIG)
-	block.WriteShort(0x335, io.STANDARD, io.BIG)
-	block.WriteShort(0x336, io.STANDARD, io.BIG)
-	block.WriteShort(824, io.STANDARD, io.BIG)
-
-	block.WriteString(p.Username)
-	block.WriteByte(3, io.STANDARD)
-	block.WriteShort(0, io.STANDARD, io.BIG)
-
-	buf.WriteByte(block.Buffer.Position, io.C)
-	buf.WriteBytes(block.Buffer)
-}
-
-func (p *Player) SendSidebarInterface(idx, val int) {
-	buf := io.NewOutBuffer(4)
-	buf.WriteHeader(p.Encryptor, 71)
-	buf.WriteShort(val, io.STANDARD, io.BIG)
-	buf.WriteByte(idx, io.A)
-	p.Send(buf)
+// Send queues buffer to be written out at the end of the current cycle.
+func (p *Player) Send(buffer *io.StreamBuffer) error {
+	p.outMutex.Lock()
+	defer p.outMutex.Unlock()
+	_, err := buffer.WriteTo(&p.outBuffer)
+	return err
 }
 
-func (p *Player) SendInventory() {
-	buf := io.NewOutBuffer(256)
-	buf.WriteVariableShortPacketHeader(p.Encryptor, 53)
-	buf.WriteShort(3214, io.STANDARD, io.BIG)
-	buf.WriteShort(len(p.Inventory), io.STANDARD, io.BIG)
-	for _, item := range p.Inventory {
-		if item.Amount > 254 {
-			buf.WriteByte(255, io.STANDARD)
-			buf.WriteInt(item.Amount, io.STANDARD, io.INVERSE_MIDDLE)
-		} else {
-			buf.WriteByte(item.Amount, io.STANDARD)
-		}
-		buf.WriteShort(item.ID+1, io.A, io.LITTLE)
+// Flush writes all queued outbound data to the socket.
+func (p *Player) Flush() error {
+	p.outMutex.Lock()
+	defer p.outMutex.Unlock()
+	if p.outBuffer.Len() == 0 {
+		return nil
 	}
-	buf.FinishVariableShortPacketHeader()
-	p.Send(buf)
-}
-
-func (p *Player) SendLogout() {
-	buf := io.NewOutBuffer(1)
-	buf.WriteHeader(p.Encryptor, 109)
-	p.Send(buf)
-}
-
-// func (p *Player) Send(buffer *io.ByteBuffer) error {
-// 	_, err := p.Socket.Write(buffer.Buffer())
-// 	return err
-// }
-
-func (p *Player) Send(buffer *io.StreamBuffer) error {
-	_, err := buffer.WriteTo(p.Socket)
+	_, err := p.outBuffer.WriteTo(p.Socket)
 	return err
 }
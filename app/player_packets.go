@@ -0,0 +1,240 @@
+package app
+
+import "rs-go-server/io"
+
+// outbound game packets
+
+// SendMapRegion loads the map around the player's current position, which
+// becomes the base for local coordinates until the next region change.
+func (p *Player) SendMapRegion() error {
+	region := *p.Position
+	p.LastRegion = &region
+	p.RegionChanged = true
+	buffer := io.NewOutBuffer(5)
+	buffer.WriteHeader(p.Encryptor, 69)
+	buffer.WriteShort(p.Position.RegionX()+6, io.A, io.BIG)
+	buffer.WriteShort(p.Position.RegionY()+6, io.STANDARD, io.BIG)
+	return p.Send(buffer)
+}
+
+func (p *Player) SendSidebarInterface(idx, val int) {
+	buf := io.NewOutBuffer(4)
+	buf.WriteHeader(p.Encryptor, 71)
+	buf.WriteShort(val, io.STANDARD, io.BIG)
+	buf.WriteByte(idx, io.A)
+	p.Send(buf)
+}
+
+// SendItems fills an item interface with items, replacing its contents.
+func (p *Player) SendItems(interfaceID int, items []Item) {
+	buf := io.NewOutBuffer(7 + len(items)*7)
+	buf.WriteVariableShortPacketHeader(p.Encryptor, 53)
+	buf.WriteShort(interfaceID, io.STANDARD, io.BIG)
+	buf.WriteShort(len(items), io.STANDARD, io.BIG)
+	for _, item := range items {
+		if item.Amount > 254 {
+			buf.WriteByte(255, io.STANDARD)
+			buf.WriteInt(item.Amount, io.STANDARD, io.INVERSE_MIDDLE)
+		} else {
+			buf.WriteByte(item.Amount, io.STANDARD)
+		}
+		buf.WriteShort(item.ID+1, io.A, io.LITTLE)
+	}
+	buf.FinishVariableShortPacketHeader()
+	p.Send(buf)
+}
+
+// SendItemSlots updates only the given slots of an item interface.
+func (p *Player) SendItemSlots(interfaceID int, ic *ItemContainer, slots []int) {
+	buf := io.NewOutBuffer(5 + len(slots)*9)
+	buf.WriteVariableShortPacketHeader(p.Encryptor, 34)
+	buf.WriteShort(interfaceID, io.STANDARD, io.BIG)
+	for _, slot := range slots {
+		// the slot is a smart, a byte below 128 and a short with the top bit
+		// set otherwise
+		if slot < 128 {
+			buf.WriteByte(slot, io.STANDARD)
+		} else {
+			buf.WriteShort(slot+0x8000, io.STANDARD, io.BIG)
+		}
+		item := ic.Get(slot)
+		buf.WriteShort(item.ID+1, io.STANDARD, io.BIG)
+		if item.Amount > 254 {
+			buf.WriteByte(255, io.STANDARD)
+			buf.WriteInt(item.Amount, io.STANDARD, io.BIG)
+		} else {
+			buf.WriteByte(item.Amount, io.STANDARD)
+		}
+	}
+	buf.FinishVariableShortPacketHeader()
+	p.Send(buf)
+}
+
+func (p *Player) SendMessage(message string) {
+	buf := io.NewOutBuffer(len(message) + 3)
+	buf.WriteVariablePacketHeader(p.Encryptor, 253)
+	buf.WriteString(message)
+	buf.FinishVariablePacketHeader()
+	p.Send(buf)
+}
+
+func (p *Player) SendLogout() {
+	buf := io.NewOutBuffer(1)
+	buf.WriteHeader(p.Encryptor, 109)
+	p.Send(buf)
+}
+
+func (p *Player) SendOpenInterface(id int) {
+	buf := io.NewOutBuffer(3)
+	buf.WriteHeader(p.Encryptor, 97)
+	buf.WriteShort(id, io.STANDARD, io.BIG)
+	p.Send(buf)
+}
+
+// SendInventoryInterface opens an interface with another one replacing the
+// inventory tab, like the bank.
+func (p *Player) SendInventoryInterface(id, sidebarID int) {
+	buf := io.NewOutBuffer(5)
+	buf.WriteHeader(p.Encryptor, 248)
+	buf.WriteShort(id, io.A, io.BIG)
+	buf.WriteShort(sidebarID, io.STANDARD, io.BIG)
+	p.Send(buf)
+}
+
+// SendEnterAmount opens the chat box dialog asking for a number, which the
+// client answers with packet 208.
+func (p *Player) SendEnterAmount() {
+	buf := io.NewOutBuffer(1)
+	buf.WriteHeader(p.Encryptor, 27)
+	p.Send(buf)
+}
+
+func (p *Player) SendCloseInterfaces() {
+	buf := io.NewOutBuffer(1)
+	buf.WriteHeader(p.Encryptor, 219)
+	p.Send(buf)
+}
+
+func (p *Player) SendChatboxInterface(id int) {
+	buf := io.NewOutBuffer(3)
+	buf.WriteHeader(p.Encryptor, 164)
+	buf.WriteShort(id, io.STANDARD, io.LITTLE)
+	p.Send(buf)
+}
+
+func (p *Player) SendInterfaceText(id int, text string) {
+	buf := io.NewOutBuffer(len(text) + 6)
+	buf.WriteVariableShortPacketHeader(p.Encryptor, 126)
+	buf.WriteString(text)
+	buf.WriteShort(id, io.A, io.BIG)
+	buf.FinishVariableShortPacketHeader()
+	p.Send(buf)
+}
+
+// SendConfig sets a client config (varp), using the short form when the value
+// fits in a byte.
+func (p *Player) SendConfig(id, value int) {
+	if value >= -128 && value <= 127 {
+		buf := io.NewOutBuffer(4)
+		buf.WriteHeader(p.Encryptor, 36)
+		buf.WriteShort(id, io.STANDARD, io.LITTLE)
+		buf.WriteByte(value, io.STANDARD)
+		p.Send(buf)
+		return
+	}
+	buf := io.NewOutBuffer(7)
+	buf.WriteHeader(p.Encryptor, 87)
+	buf.WriteShort(id, io.STANDARD, io.LITTLE)
+	buf.WriteInt(value, io.STANDARD, io.MIDDLE)
+	p.Send(buf)
+}
+
+func (p *Player) SendSkill(skill int) {
+	buf := io.NewOutBuffer(7)
+	buf.WriteHeader(p.Encryptor, 134)
+	buf.WriteByte(skill, io.STANDARD)
+	buf.WriteInt(p.Skills[skill].Experience, io.STANDARD, io.MIDDLE)
+	buf.WriteByte(p.Skills[skill].Level, io.STANDARD)
+	p.Send(buf)
+}
+
+func (p *Player) SendRunEnergy(energy int) {
+	buf := io.NewOutBuffer(2)
+	buf.WriteHeader(p.Encryptor, 110)
+	buf.WriteByte(energy, io.STANDARD)
+	p.Send(buf)
+}
+
+func (p *Player) SendWeight(weight int) {
+	buf := io.NewOutBuffer(3)
+	buf.WriteHeader(p.Encryptor, 240)
+	buf.WriteShort(weight, io.STANDARD, io.BIG)
+	p.Send(buf)
+}
+
+// SendInterfaceItemModel shows an item's model on an interface, zoom is the
+// model's scale.
+func (p *Player) SendInterfaceItemModel(id, zoom, itemID int) {
+	buf := io.NewOutBuffer(7)
+	buf.WriteHeader(p.Encryptor, 246)
+	buf.WriteShort(id, io.STANDARD, io.LITTLE)
+	buf.WriteShort(zoom, io.STANDARD, io.BIG)
+	buf.WriteShort(itemID, io.STANDARD, io.BIG)
+	p.Send(buf)
+}
+
+func (p *Player) SendInterfaceAnimation(id, animation int) {
+	buf := io.NewOutBuffer(5)
+	buf.WriteHeader(p.Encryptor, 200)
+	buf.WriteShort(id, io.STANDARD, io.BIG)
+	buf.WriteShort(animation, io.STANDARD, io.BIG)
+	p.Send(buf)
+}
+
+// SendMoveCamera moves the camera to local coordinates x, y at the given
+// height.  speed is the constant part of the movement and acceleration the
+// part proportional to the remaining distance.
+func (p *Player) SendMoveCamera(x, y, height, speed, acceleration int) {
+	p.sendCamera(166, x, y, height, speed, acceleration)
+}
+
+// SendTurnCamera points the camera at local coordinates x, y and height.
+func (p *Player) SendTurnCamera(x, y, height, speed, acceleration int) {
+	p.sendCamera(177, x, y, height, speed, acceleration)
+}
+
+func (p *Player) sendCamera(opcode, x, y, height, speed, acceleration int) {
+	buf := io.NewOutBuffer(7)
+	buf.WriteHeader(p.Encryptor, opcode)
+	buf.WriteByte(x, io.STANDARD)
+	buf.WriteByte(y, io.STANDARD)
+	buf.WriteShort(height, io.STANDARD, io.BIG)
+	buf.WriteByte(speed, io.STANDARD)
+	buf.WriteByte(acceleration, io.STANDARD)
+	p.Send(buf)
+}
+
+func (p *Player) SendShakeCamera(kind, jitter, amplitude, frequency int) {
+	buf := io.NewOutBuffer(5)
+	buf.WriteHeader(p.Encryptor, 35)
+	buf.WriteByte(kind, io.STANDARD)
+	buf.WriteByte(jitter, io.STANDARD)
+	buf.WriteByte(amplitude, io.STANDARD)
+	buf.WriteByte(frequency, io.STANDARD)
+	p.Send(buf)
+}
+
+func (p *Player) SendResetCamera() {
+	buf := io.NewOutBuffer(1)
+	buf.WriteHeader(p.Encryptor, 107)
+	p.Send(buf)
+}
+
+// SendSystemUpdate starts the client's "System update in" countdown, given
+// in game cycles.
+func (p *Player) SendSystemUpdate(cycles int) {
+	buf := io.NewOutBuffer(3)
+	buf.WriteHeader(p.Encryptor, 114)
+	buf.WriteShort(cycles, io.STANDARD, io.LITTLE)
+	p.Send(buf)
+}
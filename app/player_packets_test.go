@@ -0,0 +1,94 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"rs-go-server/crypto"
+	"testing"
+	"time"
+)
+
+// newTestPlayer returns a player without opcode encryption connected over
+// loopback, and the client's end of the connection.
+func newTestPlayer(t *testing.T) (*Player, net.Conn) {
+	t.Helper()
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := listener.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	p := NewPlayer(server)
+	p.Encryptor = crypto.NewMockISAACCipher(nil)
+	return p, client
+}
+
+// expectSent flushes p and checks the client receives exactly expected.  A
+// flush writes everything queued, so extra bytes are caught before they're
+// sent rather than by a read that has to time out.
+func expectSent(t *testing.T, p *Player, client net.Conn, expected []byte) {
+	t.Helper()
+	if queued := p.outBuffer.Bytes(); len(queued) != len(expected) {
+		t.Fatalf("queued % x, want % x", queued, expected)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	received := make([]byte, len(expected))
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(client, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, expected) {
+		t.Errorf("sent % x, want % x", received, expected)
+	}
+}
+
+func TestOutboundPackets(t *testing.T) {
+	tests := []struct {
+		name     string
+		send     func(p *Player)
+		expected []byte
+	}{
+		{"message", func(p *Player) { p.SendMessage("hi") }, []byte{253, 3, 'h', 'i', 10}},
+		{"open interface", func(p *Player) { p.SendOpenInterface(5292) }, []byte{97, 0x14, 0xac}},
+		{"close interfaces", func(p *Player) { p.SendCloseInterfaces() }, []byte{219}},
+		{"chat box interface", func(p *Player) { p.SendChatboxInterface(4882) }, []byte{164, 0x12, 0x13}},
+		{"interface text", func(p *Player) { p.SendInterfaceText(1675, "Hi") }, []byte{126, 0, 5, 'H', 'i', 10, 0x06, 0x0b}},
+		{"byte config", func(p *Player) { p.SendConfig(115, 1) }, []byte{36, 0x73, 0x00, 1}},
+		{"negative byte config", func(p *Player) { p.SendConfig(115, -1) }, []byte{36, 0x73, 0x00, 0xff}},
+		{"int config", func(p *Player) { p.SendConfig(300, 1000) }, []byte{87, 0x2c, 0x01, 0x03, 0xe8, 0x00, 0x00}},
+		{"skill", func(p *Player) {
+			p.Skills[ATTACK] = Skill{Level: 10, Experience: 1154}
+			p.SendSkill(ATTACK)
+		}, []byte{134, 0, 0x04, 0x82, 0x00, 0x00, 10}},
+		{"run energy", func(p *Player) { p.SendRunEnergy(100) }, []byte{110, 100}},
+		{"weight", func(p *Player) { p.SendWeight(25) }, []byte{240, 0, 25}},
+		{"interface item model", func(p *Player) { p.SendInterfaceItemModel(1688, 200, 1038) }, []byte{246, 0x98, 0x06, 0x00, 0xc8, 0x04, 0x0e}},
+		{"interface animation", func(p *Player) { p.SendInterfaceAnimation(591, 588) }, []byte{200, 0x02, 0x4f, 0x02, 0x4c}},
+		{"move camera", func(p *Player) { p.SendMoveCamera(10, 20, 400, 5, 10) }, []byte{166, 10, 20, 0x01, 0x90, 5, 10}},
+		{"turn camera", func(p *Player) { p.SendTurnCamera(10, 20, 400, 5, 10) }, []byte{177, 10, 20, 0x01, 0x90, 5, 10}},
+		{"shake camera", func(p *Player) { p.SendShakeCamera(1, 2, 3, 4) }, []byte{35, 1, 2, 3, 4}},
+		{"reset camera", func(p *Player) { p.SendResetCamera() }, []byte{107}},
+		{"system update", func(p *Player) { p.SendSystemUpdate(500) }, []byte{114, 0xf4, 0x01}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, client := newTestPlayer(t)
+			test.send(p)
+			expectSent(t, p, client, test.expected)
+		})
+	}
+}
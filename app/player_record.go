@@ -0,0 +1,197 @@
+package app
+
+import (
+	"rs-go-server/io"
+	"rs-go-server/repo"
+)
+
+// AutosaveCycles is how often every player in the world is saved.
+const AutosaveCycles = 100
+
+// playerKey is what a character is saved under, the username as the client
+// encodes it, so every spelling the client treats as one name shares a save.
+func playerKey(username string) string {
+	return io.LongToName(io.NameToLong(username))
+}
+
+// Record captures everything about the player that is saved between
+// sessions.
+func (p *Player) Record() *repo.PlayerRecord {
+	record := &repo.PlayerRecord{
+		Version:  repo.PLAYER_RECORD_VERSION,
+		Username: p.Username,
+		Rights:   p.Rights,
+		Position: repo.PositionRecord{X: p.Position.X, Y: p.Position.Y, Z: p.Position.Z},
+		Appearance: repo.AppearanceRecord{
+			Gender: p.Appearance.Gender,
+			Kits:   append([]int(nil), p.Appearance.Kits[:]...),
+			Colors: append([]int(nil), p.Appearance.Colors[:]...),
+		},
+		Inventory: containerRecord(p.Inventory),
+		Equipment: containerRecord(p.Equipment),
+		Bank:      containerRecord(p.Bank),
+		Settings: repo.SettingsRecord{
+			BankNoteMode:   p.BankNoteMode,
+			BankInsertMode: p.BankInsertMode,
+		},
+	}
+	for _, skill := range p.Skills {
+		record.Skills = append(record.Skills, repo.SkillRecord{Level: skill.Level, Experience: skill.Experience})
+	}
+	return record
+}
+
+// applyRecord restores a saved character onto a freshly created player.
+// Anything in the record that doesn't fit, like an invalid appearance, is
+// left at its default.
+func (p *Player) applyRecord(record *repo.PlayerRecord) {
+	p.Rights = record.Rights
+	p.Position = &Position{X: record.Position.X, Y: record.Position.Y, Z: record.Position.Z}
+
+	appearance := Appearance{Gender: record.Appearance.Gender}
+	copy(appearance.Kits[:], record.Appearance.Kits)
+	copy(appearance.Colors[:], record.Appearance.Colors)
+	if len(record.Appearance.Kits) == len(appearance.Kits) && len(record.Appearance.Colors) == len(appearance.Colors) && appearance.Valid() {
+		p.Appearance = appearance
+	}
+
+	for i, skill := range record.Skills {
+		if i >= SKILL_COUNT {
+			break
+		}
+		p.Skills.SetExperience(i, skill.Experience)
+		p.Skills.SetLevel(i, skill.Level)
+	}
+
+	applyContainerRecord(p.Inventory, record.Inventory)
+	applyContainerRecord(p.Equipment, record.Equipment)
+	p.refreshAnimations()
+	applyContainerRecord(p.Bank, record.Bank)
+
+	p.BankNoteMode = record.Settings.BankNoteMode
+	p.BankInsertMode = record.Settings.BankInsertMode
+}
+
+// loadRecord restores the player's saved character before they enter the
+// world, or starts a new one.
+func (p *Player) loadRecord(request *LoginRequest) LoginResponse {
+	record, err := p.world.LoadPlayer(p.Username)
+	if err != nil {
+		p.logger(worldLog).Error("failed to load player", "error", err)
+		return LOGIN_COULD_NOT_COMPLETE
+	}
+	if record == nil {
+		p.newCharacter()
+		return LOGIN_OK
+	}
+	p.applyRecord(record)
+	request.Record = record
+	request.Rights = record.Rights
+	return LOGIN_OK
+}
+
+// newCharacter puts a player without a saved character at the spawn point
+// with the starter items.
+func (p *Player) newCharacter() {
+	spawn := p.world.Config.Spawn
+	p.Position = &Position{X: spawn.X, Y: spawn.Y, Z: spawn.Z}
+	for _, item := range p.world.Config.Starter {
+		p.Inventory.Add(Item{item.ID, item.Amount})
+	}
+}
+
+func containerRecord(ic *ItemContainer) []repo.ItemRecord {
+	records := []repo.ItemRecord{}
+	for slot, item := range ic.Items() {
+		if !item.Empty() {
+			records = append(records, repo.ItemRecord{Slot: slot, ID: item.ID, Amount: item.Amount})
+		}
+	}
+	return records
+}
+
+func applyContainerRecord(ic *ItemContainer, records []repo.ItemRecord) {
+	ic.Clear()
+	for _, record := range records {
+		if record.Slot < 0 || record.Slot >= ic.Size() || record.ID < 0 {
+			continue
+		}
+		ic.Set(record.Slot, Item{ID: record.ID, Amount: record.Amount})
+	}
+}
+
+// LoadPlayer fetches a saved character, nil if there's none or the world has
+// no repository.  A save still waiting to be written takes precedence over
+// the repository, so a quick relog doesn't load stale data.
+func (w *World) LoadPlayer(username string) (*repo.PlayerRecord, error) {
+	if w.Repository == nil {
+		return nil, nil
+	}
+	key := playerKey(username)
+	w.saveMutex.Lock()
+	record, ok := w.pendingSaves[key]
+	w.saveMutex.Unlock()
+	if ok {
+		return record, nil
+	}
+	record, err := w.Repository.LoadPlayer(key)
+	if err == repo.ErrPlayerNotFound {
+		return nil, nil
+	}
+	return record, err
+}
+
+// SavePlayer snapshots p and queues it to be written in the background.  It
+// never blocks the game loop: a player has a single pending save, replaced
+// by newer snapshots until the saver gets to it.
+func (w *World) SavePlayer(p *Player) {
+	if w.Repository == nil {
+		return
+	}
+	record := p.Record()
+	w.saveMutex.Lock()
+	w.pendingSaves[playerKey(record.Username)] = record
+	w.saveMutex.Unlock()
+	select {
+	case w.saves <- struct{}{}:
+	default: // the saver has already been woken up
+	}
+}
+
+// runSaver writes the pending saves whenever it's woken up, and once more
+// after the world closes w.saves.
+func (w *World) runSaver() {
+	defer close(w.saverDone)
+	for range w.saves {
+		w.writePendingSaves()
+	}
+	w.writePendingSaves()
+}
+
+func (w *World) writePendingSaves() {
+	w.saveMutex.Lock()
+	records := make([]*repo.PlayerRecord, 0, len(w.pendingSaves))
+	for _, record := range w.pendingSaves {
+		records = append(records, record)
+	}
+	w.saveMutex.Unlock()
+	for _, record := range records {
+		key := playerKey(record.Username)
+		if err := w.Repository.SavePlayer(key, record); err != nil {
+			worldLog.Error("failed to save player", "username", record.Username, "error", err)
+		}
+		w.saveMutex.Lock()
+		if w.pendingSaves[key] == record {
+			delete(w.pendingSaves, key)
+		}
+		w.saveMutex.Unlock()
+	}
+}
+
+func (w *World) autosave(players []*Player) {
+	for _, p := range players {
+		if p.active {
+			w.SavePlayer(p)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"rs-go-server/repo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowRepository holds every save until release is closed.
+type slowRepository struct {
+	release chan struct{}
+	mutex   sync.Mutex
+	saved   map[string]*repo.PlayerRecord
+}
+
+func (r *slowRepository) LoadPlayer(key string) (*repo.PlayerRecord, error) {
+	return nil, repo.ErrPlayerNotFound
+}
+
+func (r *slowRepository) SavePlayer(key string, record *repo.PlayerRecord) error {
+	<-r.release
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.saved[key] = record
+	return nil
+}
+
+func TestSavePlayerDoesNotBlock(t *testing.T) {
+	repository := &slowRepository{release: make(chan struct{}), saved: make(map[string]*repo.PlayerRecord)}
+	w := newTestWorld()
+	w.Repository = repository
+	go w.runSaver()
+	p := NewPlayer(nil)
+	p.world = w
+	p.Username = "bob"
+
+	saved := make(chan struct{})
+	go func() {
+		for i := 0; i <= MaxPlayers*2; i++ {
+			p.Rights = i % 3
+			w.SavePlayer(p)
+		}
+		close(saved)
+	}()
+	select {
+	case <-saved:
+	case <-time.After(5 * time.Second):
+		t.Fatal("saving blocked while the repository was busy")
+	}
+
+	close(repository.release)
+	close(w.saves)
+	<-w.saverDone
+	if record := repository.saved["bob"]; record == nil || record.Rights != (MaxPlayers*2)%3 {
+		t.Errorf("the latest save wasn't written: %+v", record)
+	}
+}
+
+func TestApplyRecordClampsSkills(t *testing.T) {
+	p := NewPlayer(nil)
+	p.applyRecord(&repo.PlayerRecord{Skills: []repo.SkillRecord{
+		{Level: 1000, Experience: 1 << 40},
+		{Level: -5, Experience: -100},
+		{Level: 50, Experience: 101333},
+	}})
+	expected := []Skill{
+		{Level: MaxBoostedLevel, Experience: MaxSkillExperience},
+		{Level: 0, Experience: 0},
+		{Level: 50, Experience: 101333},
+	}
+	for i, skill := range expected {
+		if p.Skills[i] != skill {
+			t.Errorf("skill %d is %+v, want %+v", i, p.Skills[i], skill)
+		}
+	}
+}
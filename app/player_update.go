@@ -0,0 +1,330 @@
+package app
+
+import "rs-go-server/io"
+
+const (
+	UPDATE_FACE_ENTITY     = 0x1
+	UPDATE_FACE_POSITION   = 0x2
+	UPDATE_FORCED_CHAT     = 0x4
+	UPDATE_ANIMATION       = 0x8
+	UPDATE_APPEARANCE      = 0x10
+	UPDATE_HIT             = 0x20
+	UPDATE_EXTENDED        = 0x40 // mask continues in a second byte
+	UPDATE_CHAT            = 0x80
+	UPDATE_GRAPHIC         = 0x100
+	UPDATE_HIT_2           = 0x200
+	UPDATE_FORCED_MOVEMENT = 0x400
+
+	// sent as the face entity index to stop facing anything
+	FACE_NONE = 0xFFFF
+
+	// distance in tiles within which players see each other
+	ViewDistance = 15
+	// the client's local player list is indexed by a byte
+	MaxLocalPlayers = 255
+	// spreads the appearance blocks of a crowd over several cycles
+	MaxLocalAdditions = 20
+	// the client reads a packet into a 5000 byte buffer
+	MaxUpdateSize = 5000
+
+	// the most bits a local player's movement takes, and adding one
+	maxMovementBits = 10
+	addPlayerBits   = 23
+	// the 11 bit index of 2047 that ends the player list
+	endMarkerBits = 11
+)
+
+// sendUpdate writes the player updating packet.  Local players and update
+// blocks that would take it past MaxUpdateSize are left for the next cycle:
+// players aren't added, and a local player whose update doesn't fit is
+// removed, to be added again with its full appearance once there's room.
+func (p *Player) sendUpdate() error {
+	out := io.NewOutBuffer(MaxUpdateSize)
+	block := io.NewOutBuffer(MaxUpdateSize)
+	// each other player's block is written here first to see if it fits
+	state := io.NewOutBuffer(MaxUpdateSize)
+
+	// fits reports whether bits more of the player list and size more of
+	// update blocks keep the packet within MaxUpdateSize, with reserved bits
+	// kept back for the rest of the list
+	fits := func(bits, size, reserved int) bool {
+		total := (out.BitPosition()+bits+reserved+endMarkerBits+7)/8 + block.Buffer.Position + size
+		return total <= MaxUpdateSize
+	}
+
+	out.WriteVariableShortPacketHeader(p.Encryptor, 81)
+	out.SetAccessType(io.BIT_ACCESS)
+
+	p.updateLocalPlayerMovement(out)
+	if p.UpdateFlags != 0 {
+		p.updateState(p, block, false)
+	}
+
+	out.WriteBits(8, len(p.LocalPlayers))
+	locals := make([]*Player, 0, len(p.LocalPlayers))
+	for i, other := range p.LocalPlayers {
+		reserved := (len(p.LocalPlayers) - i - 1) * maxMovementBits
+		if other.active && !other.Teleporting && other.Position.WithinDistance(p.Position, ViewDistance) {
+			state.Reset()
+			if other.UpdateFlags != 0 {
+				other.updateState(p, state, false)
+			}
+			if fits(maxMovementBits, state.Buffer.Position, reserved) {
+				other.updateMovement(out)
+				block.WriteBytes(state.Buffer)
+				locals = append(locals, other)
+				continue
+			}
+		}
+		out.WriteBit(true)
+		out.WriteBits(2, 3)
+	}
+	p.LocalPlayers = locals
+
+	added := 0
+	for _, other := range p.world.Players() {
+		if len(p.LocalPlayers) >= MaxLocalPlayers || added >= MaxLocalAdditions {
+			break
+		}
+		if other == p || !other.active || !other.Position.WithinDistance(p.Position, ViewDistance) || p.hasLocalPlayer(other) {
+			continue
+		}
+		state.Reset()
+		other.updateState(p, state, true)
+		if !fits(addPlayerBits, state.Buffer.Position, 0) {
+			break
+		}
+		p.addLocalPlayer(out, other)
+		block.WriteBytes(state.Buffer)
+		added++
+	}
+
+	if block.Buffer.Position > 0 {
+		out.WriteBits(11, 2047)
+		out.SetAccessType(io.BYTE_ACCESS)
+		out.WriteBytes(block.Buffer)
+	} else {
+		out.SetAccessType(io.BYTE_ACCESS)
+	}
+
+	out.FinishVariableShortPacketHeader()
+	return p.Send(out)
+}
+
+type Animation struct {
+	ID, Delay int
+}
+
+type Graphic struct {
+	ID, Height, Delay int
+}
+
+type Hit struct {
+	Damage, Type            int
+	Hitpoints, MaxHitpoints int
+}
+
+type ChatMessage struct {
+	Effects, Color int
+	Text           []byte // packed with io.PackText
+}
+
+// ForcedMovement moves the player from Start to End regardless of the walking
+// queue, with delays given in client cycles of 20ms.
+type ForcedMovement struct {
+	Start, End           Position
+	StartDelay, EndDelay int
+	Direction            int
+}
+
+func (p *Player) PlayAnimation(animation Animation) {
+	p.animation = animation
+	p.UpdateFlags |= UPDATE_ANIMATION
+}
+
+func (p *Player) PlayGraphic(graphic Graphic) {
+	p.graphic = graphic
+	p.UpdateFlags |= UPDATE_GRAPHIC
+}
+
+// ForceChat shows text above the player's head without it going to the chat
+// box.
+func (p *Player) ForceChat(text string) {
+	p.forcedChat = text
+	p.UpdateFlags |= UPDATE_FORCED_CHAT
+}
+
+func (p *Player) Chat(message *ChatMessage) {
+	p.chatMessage = message
+	p.UpdateFlags |= UPDATE_CHAT
+}
+
+// FaceEntity turns the player towards an npc index, or a player index plus
+// 32768.  FACE_NONE resets it.
+func (p *Player) FaceEntity(index int) {
+	p.faceEntity = index
+	p.UpdateFlags |= UPDATE_FACE_ENTITY
+}
+
+func (p *Player) FacePlayer(other *Player) {
+	p.FaceEntity(other.Index + 32768)
+}
+
+func (p *Player) FacePosition(position Position) {
+	p.facePosition = position
+	p.UpdateFlags |= UPDATE_FACE_POSITION
+}
+
+// ShowHit displays a hit splat, a second hit in the same cycle uses the
+// secondary hit slot and any further ones are dropped.
+func (p *Player) ShowHit(hit Hit) {
+	switch {
+	case p.UpdateFlags&UPDATE_HIT == 0:
+		p.hits[0] = hit
+		p.UpdateFlags |= UPDATE_HIT
+	case p.UpdateFlags&UPDATE_HIT_2 == 0:
+		p.hits[1] = hit
+		p.UpdateFlags |= UPDATE_HIT_2
+	}
+}
+
+func (p *Player) ForceMove(movement ForcedMovement) {
+	p.forcedMovement = movement
+	p.UpdateFlags |= UPDATE_FORCED_MOVEMENT
+}
+
+func (p *Player) hasLocalPlayer(other *Player) bool {
+	for _, local := range p.LocalPlayers {
+		if local == other {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Player) addLocalPlayer(buf *io.StreamBuffer, other *Player) {
+	p.LocalPlayers = append(p.LocalPlayers, other)
+	buf.WriteBits(11, other.Index)
+	buf.WriteBit(true) // update required, for the appearance
+	buf.WriteBit(true) // discard walking queue
+	buf.WriteBits(5, other.Position.Y-p.Position.Y)
+	buf.WriteBits(5, other.Position.X-p.Position.X)
+}
+
+func (p *Player) updateLocalPlayerMovement(buf *io.StreamBuffer) {
+	switch {
+	case p.Teleporting || p.RegionChanged:
+		buf.WriteBit(true)
+		buf.WriteBits(2, 3)
+		buf.WriteBits(2, p.Position.Z)
+		buf.WriteBit(p.Teleporting) // discard walking queue
+		buf.WriteBit(p.UpdateFlags != 0)
+		buf.WriteBits(7, p.Position.RelativeY(p.LastRegion))
+		buf.WriteBits(7, p.Position.RelativeX(p.LastRegion))
+	default:
+		p.updateMovement(buf)
+	}
+}
+
+// updateMovement writes the walk or run steps taken this cycle.
+func (p *Player) updateMovement(buf *io.StreamBuffer) {
+	switch {
+	case p.PrimaryDirection == NO_DIRECTION:
+		if p.UpdateFlags != 0 {
+			buf.WriteBit(true)
+			buf.WriteBits(2, 0)
+		} else {
+			buf.WriteBit(false)
+		}
+	case p.SecondaryDirection == NO_DIRECTION:
+		buf.WriteBit(true)
+		buf.WriteBits(2, 1)
+		buf.WriteBits(3, p.PrimaryDirection)
+		buf.WriteBit(p.UpdateFlags != 0)
+	default:
+		buf.WriteBit(true)
+		buf.WriteBits(2, 2)
+		buf.WriteBits(3, p.PrimaryDirection)
+		buf.WriteBits(3, p.SecondaryDirection)
+		buf.WriteBit(p.UpdateFlags != 0)
+	}
+}
+
+// updateState writes the player's update block as seen by observer.  The
+// blocks must be written in the order the client reads them.  forceAppearance
+// is set when the observer has just added the player and has never seen
+// their looks.
+func (p *Player) updateState(observer *Player, buf *io.StreamBuffer, forceAppearance bool) {
+	mask := p.UpdateFlags
+	if forceAppearance {
+		mask |= UPDATE_APPEARANCE
+	}
+	if observer == p {
+		mask &^= UPDATE_CHAT // the client echoes its own chat
+	}
+
+	if mask >= 0x100 {
+		mask |= UPDATE_EXTENDED
+		buf.WriteShort(mask, io.STANDARD, io.LITTLE)
+	} else {
+		buf.WriteByte(mask, io.STANDARD)
+	}
+
+	if mask&UPDATE_FORCED_MOVEMENT != 0 {
+		p.appendForcedMovement(observer, buf)
+	}
+	if mask&UPDATE_GRAPHIC != 0 {
+		buf.WriteShort(p.graphic.ID, io.STANDARD, io.LITTLE)
+		buf.WriteInt(p.graphic.Height<<16|p.graphic.Delay&0xFFFF, io.STANDARD, io.BIG)
+	}
+	if mask&UPDATE_ANIMATION != 0 {
+		buf.WriteShort(p.animation.ID, io.STANDARD, io.LITTLE)
+		buf.WriteByte(p.animation.Delay, io.C)
+	}
+	if mask&UPDATE_FORCED_CHAT != 0 {
+		buf.WriteString(p.forcedChat)
+	}
+	if mask&UPDATE_CHAT != 0 {
+		buf.WriteShort(p.chatMessage.Color<<8|p.chatMessage.Effects, io.STANDARD, io.LITTLE)
+		buf.WriteByte(p.Rights, io.STANDARD)
+		buf.WriteByte(len(p.chatMessage.Text), io.C)
+		buf.WriteBytesReverse(io.NewByteBufferWithBytes(p.chatMessage.Text))
+	}
+	if mask&UPDATE_FACE_ENTITY != 0 {
+		buf.WriteShort(p.faceEntity, io.STANDARD, io.LITTLE)
+	}
+	if mask&UPDATE_APPEARANCE != 0 {
+		p.appendAppearance(buf)
+	}
+	if mask&UPDATE_FACE_POSITION != 0 {
+		buf.WriteShort(p.facePosition.X*2+1, io.A, io.LITTLE)
+		buf.WriteShort(p.facePosition.Y*2+1, io.STANDARD, io.LITTLE)
+	}
+	if mask&UPDATE_HIT != 0 {
+		hit := p.hits[0]
+		buf.WriteByte(hit.Damage, io.STANDARD)
+		buf.WriteByte(hit.Type, io.A)
+		buf.WriteByte(hit.Hitpoints, io.C)
+		buf.WriteByte(hit.MaxHitpoints, io.STANDARD)
+	}
+	if mask&UPDATE_HIT_2 != 0 {
+		hit := p.hits[1]
+		buf.WriteByte(hit.Damage, io.STANDARD)
+		buf.WriteByte(hit.Type, io.S)
+		buf.WriteByte(hit.Hitpoints, io.STANDARD)
+		buf.WriteByte(hit.MaxHitpoints, io.C)
+	}
+}
+
+// appendForcedMovement writes the movement in coordinates local to the
+// observer's loaded map area.
+func (p *Player) appendForcedMovement(observer *Player, buf *io.StreamBuffer) {
+	fm := &p.forcedMovement
+	buf.WriteByte(fm.Start.RelativeX(observer.LastRegion), io.S)
+	buf.WriteByte(fm.Start.RelativeY(observer.LastRegion), io.S)
+	buf.WriteByte(fm.End.RelativeX(observer.LastRegion), io.S)
+	buf.WriteByte(fm.End.RelativeY(observer.LastRegion), io.S)
+	buf.WriteShort(fm.StartDelay, io.A, io.LITTLE)
+	buf.WriteShort(fm.EndDelay, io.A, io.BIG)
+	buf.WriteByte(fm.Direction, io.S)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bytes"
+	"rs-go-server/crypto"
+	rsio "rs-go-server/io"
+	"strings"
+	"testing"
+)
+
+// crowd registers count players standing on the same tile, in the world
+// without running it.
+func crowd(t *testing.T, count int) []*Player {
+	t.Helper()
+	w := newTestWorld()
+	players := make([]*Player, count)
+	for i := range players {
+		p := NewPlayer(nil)
+		p.world = w
+		p.Encryptor = crypto.NewMockISAACCipher(nil)
+		p.Username = "player"
+		p.Position = &Position{X: 3222, Y: 3218}
+		p.LastRegion = &Position{X: 3222, Y: 3218}
+		p.Teleporting = false
+		p.active = true
+		if err := w.Register(p); err != nil {
+			t.Fatal(err)
+		}
+		players[i] = p
+	}
+	return players
+}
+
+// Every player keeps chatting and changing their looks, far more than fits in
+// one packet.
+func TestUpdateSizeInCrowd(t *testing.T) {
+	players := crowd(t, 300)
+	for _, p := range players {
+		p.ForceChat(strings.Repeat("crowded ", 12))
+		p.UpdateFlags |= UPDATE_APPEARANCE
+		p.Chat(&ChatMessage{Text: rsio.PackText(strings.Repeat("busy ", 16))})
+	}
+	observer := players[0]
+	for cycle := 0; cycle < 20; cycle++ {
+		observer.sendUpdate()
+		if size := observer.outBuffer.Len(); size > MaxUpdateSize {
+			t.Fatalf("cycle %d: update of %d bytes", cycle, size)
+		}
+		observer.outBuffer.Reset()
+	}
+	if len(observer.LocalPlayers) == 0 {
+		t.Error("no players were added")
+	}
+}
+
+func TestUpdateAddsWholeCrowd(t *testing.T) {
+	players := crowd(t, 300)
+	observer := players[0]
+	for cycle := 0; cycle < 20; cycle++ {
+		observer.sendUpdate()
+		if size := observer.outBuffer.Len(); size > MaxUpdateSize {
+			t.Fatalf("cycle %d: update of %d bytes", cycle, size)
+		}
+		observer.outBuffer.Reset()
+		for _, p := range players {
+			p.resetUpdateFlags()
+		}
+	}
+	if len(observer.LocalPlayers) != MaxLocalPlayers {
+		t.Errorf("%d local players, want %d", len(observer.LocalPlayers), MaxLocalPlayers)
+	}
+}
+
+func TestUpdateBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		update   func(p *Player)
+		expected []byte
+	}{
+		{"face entity", func(p *Player) { p.FaceEntity(0x1234) }, []byte{0x01, 0x34, 0x12}},
+		{"face position", func(p *Player) { p.FacePosition(Position{X: 3222, Y: 3218}) }, []byte{0x02, 0xad, 0x19, 0x25, 0x19}},
+		{"forced chat", func(p *Player) { p.ForceChat("hi") }, []byte{0x04, 'h', 'i', 10}},
+		{"animation", func(p *Player) { p.PlayAnimation(Animation{ID: 0x0326, Delay: 2}) }, []byte{0x08, 0x26, 0x03, 0xfe}},
+		{"appearance", func(p *Player) { p.UpdateFlags |= UPDATE_APPEARANCE }, []byte{
+			0x10, 0xd1,
+			0, 0, // gender, head icon
+			0, 0, 0, 0, // hat, cape, amulet, weapon
+			0x01, 0x12, 0, 0x01, 0x1a, 0x01, 0x24, 0x01, 0x00, 0x01, 0x21, 0x01, 0x2a, 0x01, 0x0a, // body kits
+			7, 8, 9, 5, 0, // colors
+			0x03, 0x28, 0x03, 0x37, 0x03, 0x33, 0x03, 0x34, 0x03, 0x35, 0x03, 0x36, 0x03, 0x38, // animations
+			'b', 'o', 'b', 10, 3, 0, 0,
+		}},
+		{"hit", func(p *Player) { p.ShowHit(Hit{Damage: 5, Type: 1, Hitpoints: 7, MaxHitpoints: 10}) }, []byte{0x20, 0x05, 0x81, 0xf9, 0x0a}},
+		{"chat", func(p *Player) { p.Chat(&ChatMessage{Effects: 1, Color: 2, Text: []byte{0xab, 0xcd}}) }, []byte{0x80, 0x01, 0x02, 0x00, 0xfe, 0xcd, 0xab}},
+		{"graphic", func(p *Player) { p.PlayGraphic(Graphic{ID: 0x0102, Height: 100, Delay: 5}) }, []byte{0x40, 0x01, 0x02, 0x01, 0x00, 0x64, 0x00, 0x05}},
+		{"second hit", func(p *Player) {
+			p.ShowHit(Hit{Damage: 1, Type: 0, Hitpoints: 9, MaxHitpoints: 10})
+			p.ShowHit(Hit{Damage: 2, Type: 1, Hitpoints: 8, MaxHitpoints: 10})
+		}, []byte{0x60, 0x02, 0x01, 0x80, 0xf7, 0x0a, 0x02, 0x7f, 0x08, 0xf6}},
+		{"forced movement", func(p *Player) {
+			p.ForceMove(ForcedMovement{
+				Start: Position{X: 3222, Y: 3218}, End: Position{X: 3224, Y: 3218},
+				StartDelay: 0x10, EndDelay: 0x20, Direction: 1,
+			})
+		}, []byte{0x40, 0x04, 0x4a, 0x4e, 0x48, 0x4e, 0x90, 0x00, 0x00, 0xa0, 0x7f}},
+		{"order", func(p *Player) {
+			p.FaceEntity(0x1234)
+			p.PlayAnimation(Animation{ID: 0x0326, Delay: 2})
+			p.PlayGraphic(Graphic{ID: 0x0102, Height: 100, Delay: 5})
+		}, []byte{0x49, 0x01, 0x02, 0x01, 0x00, 0x64, 0x00, 0x05, 0x26, 0x03, 0xfe, 0x34, 0x12}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			players := crowd(t, 2)
+			p, observer := players[0], players[1]
+			p.Username = "bob"
+			p.UpdateFlags = 0
+			test.update(p)
+			buf := rsio.NewOutBuffer(256)
+			p.updateState(observer, buf, false)
+			if block := buf.Buffer.Buffer(); !bytes.Equal(block, test.expected) {
+				t.Errorf("wrote % x, want % x", block, test.expected)
+			}
+		})
+	}
+}
+
+func TestOwnChatNotEchoed(t *testing.T) {
+	p := crowd(t, 1)[0]
+	p.UpdateFlags = 0
+	p.Chat(&ChatMessage{Text: []byte{0xab}})
+	buf := rsio.NewOutBuffer(16)
+	p.updateState(p, buf, false)
+	if block := buf.Buffer.Buffer(); !bytes.Equal(block, []byte{0x00}) {
+		t.Errorf("wrote % x for the player's own chat, want 00", block)
+	}
+}
@@ -13,9 +13,29 @@ func (p *Position) RegionY() int {
 }
 
 func (p *Position) LocalX() int {
-	return p.X - 8 * p.RegionX()
+	return p.X - 8*p.RegionX()
 }
 
 func (p *Position) LocalY() int {
-	return p.Y - 8 * p.RegionY()
-}
\ No newline at end of file
+	return p.Y - 8*p.RegionY()
+}
+
+// RelativeX is the local x coordinate within the map area loaded around base.
+func (p *Position) RelativeX(base *Position) int {
+	return p.X - 8*base.RegionX()
+}
+
+// RelativeY is the local y coordinate within the map area loaded around base.
+func (p *Position) RelativeY(base *Position) int {
+	return p.Y - 8*base.RegionY()
+}
+
+// WithinDistance reports whether other is on the same plane and at most
+// distance tiles away on both axes.
+func (p *Position) WithinDistance(other *Position, distance int) bool {
+	if p.Z != other.Z {
+		return false
+	}
+	dx, dy := p.X-other.X, p.Y-other.Y
+	return dx <= distance && dx >= -distance && dy <= distance && dy >= -distance
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	ATTACK = iota
+	DEFENCE
+	STRENGTH
+	HITPOINTS
+	RANGED
+	PRAYER
+	MAGIC
+	COOKING
+	WOODCUTTING
+	FLETCHING
+	FISHING
+	FIREMAKING
+	CRAFTING
+	SMITHING
+	MINING
+	HERBLORE
+	AGILITY
+	THIEVING
+	SLAYER
+	FARMING
+	RUNECRAFTING
+
+	SKILL_COUNT = 21
+
+	MaxSkillLevel      = 99
+	MaxSkillExperience = 200000000
+	// boosts can take a level past 99, up to what the client reads into a
+	// byte
+	MaxBoostedLevel = 255
+)
+
+var skillNames = [SKILL_COUNT]string{
+	"Attack", "Defence", "Strength", "Hitpoints", "Ranged", "Prayer", "Magic",
+	"Cooking", "Woodcutting", "Fletching", "Fishing", "Firemaking", "Crafting",
+	"Smithing", "Mining", "Herblore", "Agility", "Thieving", "Slayer", "Farming",
+	"Runecrafting",
+}
+
+var experienceTable = func() [MaxSkillLevel + 1]int {
+	var table [MaxSkillLevel + 1]int
+	points := 0
+	for level := 1; level < MaxSkillLevel; level++ {
+		points += int(math.Floor(float64(level) + 300*math.Pow(2, float64(level)/7)))
+		table[level+1] = points / 4
+	}
+	return table
+}()
+
+type Skill struct {
+	Level      int // current, possibly boosted or drained, level
+	Experience int
+}
+
+type Skills [SKILL_COUNT]Skill
+
+func NewSkills() *Skills {
+	skills := &Skills{}
+	for i := range skills {
+		skills[i].Level = 1
+	}
+	skills[HITPOINTS] = Skill{Level: 10, Experience: ExperienceForLevel(10)}
+	return skills
+}
+
+func SkillName(skill int) string {
+	return skillNames[skill]
+}
+
+// SkillByName returns the skill with the given name, ignoring case, or -1.
+func SkillByName(name string) int {
+	for skill, skillName := range skillNames {
+		if strings.EqualFold(skillName, name) {
+			return skill
+		}
+	}
+	return -1
+}
+
+func ExperienceForLevel(level int) int {
+	if level < 1 {
+		return 0
+	}
+	if level > MaxSkillLevel {
+		level = MaxSkillLevel
+	}
+	return experienceTable[level]
+}
+
+func LevelForExperience(experience int) int {
+	for level := MaxSkillLevel; level > 1; level-- {
+		if experience >= experienceTable[level] {
+			return level
+		}
+	}
+	return 1
+}
+
+// SetExperience sets the experience of a skill, kept between 0 and
+// MaxSkillExperience.
+func (s *Skills) SetExperience(skill, experience int) {
+	s[skill].Experience = min(max(experience, 0), MaxSkillExperience)
+}
+
+// SetLevel sets the current level of a skill, kept between 0 and
+// MaxBoostedLevel.
+func (s *Skills) SetLevel(skill, level int) {
+	s[skill].Level = min(max(level, 0), MaxBoostedLevel)
+}
+
+// MaxLevel is the level the skill's experience is worth, ignoring boosts.
+func (s *Skills) MaxLevel(skill int) int {
+	return LevelForExperience(s[skill].Experience)
+}
+
+func (s *Skills) CombatLevel() int {
+	base := float64(s.MaxLevel(DEFENCE)+s.MaxLevel(HITPOINTS)+s.MaxLevel(PRAYER)/2) * 0.25
+	melee := float64(s.MaxLevel(ATTACK)+s.MaxLevel(STRENGTH)) * 0.325
+	ranged := float64(s.MaxLevel(RANGED)*3/2) * 0.325
+	magic := float64(s.MaxLevel(MAGIC)*3/2) * 0.325
+	return int(base + math.Max(melee, math.Max(ranged, magic)))
+}
+
+func (s *Skills) TotalLevel() int {
+	total := 0
+	for i := range s {
+		total += s.MaxLevel(i)
+	}
+	return total
+}
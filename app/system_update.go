@@ -0,0 +1,81 @@
+package app
+
+import "time"
+
+// SYSTEM_UPDATE_STEP is what one unit of the client's update timer counts
+// down, independent of the server's cycle length.
+const SYSTEM_UPDATE_STEP = 600 * time.Millisecond
+
+// RejectDuringUpdate refuses logins once a system update has been started.
+func RejectDuringUpdate(w *World, request *LoginRequest) LoginResponse {
+	if w.Updating() {
+		return LOGIN_UPDATE_IN_PROGRESS
+	}
+	return LOGIN_OK
+}
+
+// Updating reports whether a system update is counting down or the world is
+// shutting down.
+func (w *World) Updating() bool {
+	return w.updating.Load()
+}
+
+// SystemUpdate shows every player a countdown and shuts the world down once
+// it runs out.  It must be called from the game loop.
+func (w *World) SystemUpdate(delay time.Duration) {
+	w.updating.Store(true)
+	// the current cycle is counted once it ends
+	w.updateCycle = w.cycle + 1 + int((delay+w.Config.CycleTime()-1)/w.Config.CycleTime())
+	worldLog.Info("system update", "delay", delay)
+	for _, p := range w.Players() {
+		if p.active {
+			p.SendSystemUpdate(int(delay / SYSTEM_UPDATE_STEP))
+		}
+	}
+}
+
+// updateDue reports whether a started system update has counted down.
+func (w *World) updateDue() bool {
+	return w.updateCycle > 0 && w.cycle >= w.updateCycle
+}
+
+// Stop asks the game loop to shut down at the end of the current cycle.  It
+// is safe to call from any goroutine and more than once.
+func (w *World) Stop() {
+	w.updating.Store(true)
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// Done is closed once the world has shut down and every save is written.
+func (w *World) Done() <-chan struct{} {
+	return w.done
+}
+
+// shutdown logs everyone out, saving them, and waits up to the configured
+// timeout for the saves to be written.
+func (w *World) shutdown() {
+	defer close(w.done)
+	w.updating.Store(true)
+	worldLog.Info("shutting down")
+	for _, p := range w.Players() {
+		if p.active {
+			p.SendLogout()
+			p.Flush()
+		}
+		w.disconnect(p)
+	}
+
+	close(w.saves)
+	select {
+	case <-w.saverDone:
+	case <-time.After(w.Config.ShutdownTimeout()):
+		worldLog.Error("timed out waiting for saves to be written")
+		return
+	}
+	if closer, ok := w.Repository.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			worldLog.Error("failed to close player repository", "error", err)
+		}
+	}
+	worldLog.Info("shut down")
+}
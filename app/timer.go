@@ -1,8 +1,12 @@
 package app
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 type Timer struct {
+	mutex           sync.Mutex
 	lastTick        time.Time
 	timeoutDuration time.Duration
 }
@@ -12,10 +16,14 @@ func NewTimer(timeoutDuration time.Duration) *Timer {
 }
 
 func (t *Timer) Elapsed() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	return time.Now().Sub(t.lastTick)
 }
 
 func (t *Timer) Tick() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.lastTick = time.Now()
 }
 
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"errors"
+	"rs-go-server/config"
+	"rs-go-server/crypto"
+	"rs-go-server/io"
+	"rs-go-server/repo"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxPlayers is the size of the player table, index 0 is never used so usable
+// indices run from 1 to MaxPlayers-1.
+const MaxPlayers = config.MAX_PLAYERS + 1
+
+var ErrWorldFull = errors.New("app/world: no free player index")
+
+// World drives the game loop.  Every cycle runs the same phases in a fixed
+// order: inbound packets, game logic, player updating and finally flushing
+// outbound data.  All game state is only ever touched from this loop, the
+// per-connection reader goroutines just decode and queue packets.
+type World struct {
+	Config *config.Config
+	// RSAKey is the private key used to decrypt the login block, nil when the
+	// client sends it as plaintext.
+	RSAKey *crypto.RSAKey
+	// LoginValidators run in order for every login, the first response other
+	// than LOGIN_OK rejects it.
+	LoginValidators []LoginValidator
+	// Repository stores characters between sessions, nil to start every
+	// login as a new character.
+	Repository repo.PlayerRepository
+	// Accounts holds the credentials logins are checked against, nil to
+	// accept any password.
+	Accounts repo.AccountRepository
+
+	loginMutex  sync.Mutex
+	mutex       sync.RWMutex
+	players     [MaxPlayers]*Player
+	freeIndices []int
+	logins      chan *Player
+	cycle       int
+
+	saveMutex    sync.Mutex
+	pendingSaves map[string]*repo.PlayerRecord
+	saves        chan struct{} // wakes up the saver
+	saverDone    chan struct{}
+
+	updating    atomic.Bool
+	updateCycle int
+	stop        chan struct{}
+	stopOnce    sync.Once
+	done        chan struct{}
+}
+
+func NewWorld(cfg *config.Config) *World {
+	w := &World{
+		Config: cfg,
+		LoginValidators: []LoginValidator{
+			RejectDuringUpdate,
+			RejectInvalidUsername,
+			RejectDuplicateLogin,
+			RejectDisabledAccount,
+			LimitConnectionsPerAddress(cfg.Login.MaxConnectionsPerAddress),
+		},
+		logins:       make(chan *Player, MaxPlayers),
+		pendingSaves: make(map[string]*repo.PlayerRecord),
+		saves:        make(chan struct{}, 1),
+		saverDone:    make(chan struct{}),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	w.freeIndices = make([]int, 0, MaxPlayers-1)
+	for i := MaxPlayers - 1; i > 0; i-- {
+		w.freeIndices = append(w.freeIndices, i)
+	}
+	return w
+}
+
+// Register assigns p the lowest free index and adds it to the player table.
+func (w *World) Register(p *Player) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.freeIndices) == 0 || MaxPlayers-1-len(w.freeIndices) >= w.Config.Server.MaxPlayers {
+		return ErrWorldFull
+	}
+	last := len(w.freeIndices) - 1
+	p.Index = w.freeIndices[last]
+	w.freeIndices = w.freeIndices[:last]
+	w.players[p.Index] = p
+	return nil
+}
+
+// Unregister removes p from the player table and recycles its index.
+func (w *World) Unregister(p *Player) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if p.Index <= 0 || p.Index >= MaxPlayers || w.players[p.Index] != p {
+		return
+	}
+	w.players[p.Index] = nil
+	// keep the free list sorted descending so the lowest index is reused first
+	i := len(w.freeIndices)
+	for i > 0 && w.freeIndices[i-1] < p.Index {
+		i--
+	}
+	w.freeIndices = append(w.freeIndices, 0)
+	copy(w.freeIndices[i+1:], w.freeIndices[i:])
+	w.freeIndices[i] = p.Index
+}
+
+func (w *World) Player(index int) *Player {
+	if index <= 0 || index >= MaxPlayers {
+		return nil
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.players[index]
+}
+
+// PlayerByUsername finds a player by any spelling of their name the client
+// treats as the same, comparing the base 37 encodings accounts and saves are
+// keyed by.
+func (w *World) PlayerByUsername(username string) *Player {
+	name := io.NameToLong(username)
+	if name == 0 {
+		return nil
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	for _, p := range w.players {
+		if p != nil && io.NameToLong(p.Username) == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Players returns a snapshot of all registered players.
+func (w *World) Players() []*Player {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	players := make([]*Player, 0, MaxPlayers-1-len(w.freeIndices))
+	for _, p := range w.players {
+		if p != nil {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+func (w *World) PlayerCount() int {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return MaxPlayers - 1 - len(w.freeIndices)
+}
+
+func (w *World) Run() {
+	ticker := time.NewTicker(w.Config.CycleTime())
+	defer ticker.Stop()
+	go w.runSaver()
+	defer w.shutdown()
+	for {
+		select {
+		case <-ticker.C:
+			w.Tick()
+			if w.updateDue() {
+				return
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *World) Tick() {
+	w.processLogins()
+	players := w.Players()
+	w.processInbound(players)
+	w.processLogic(players)
+	w.updatePlayers(players)
+	w.flush(players)
+	w.cycle++
+	if w.cycle%AutosaveCycles == 0 {
+		w.autosave(players)
+	}
+}
+
+// queueLogin hands a player that has completed the login handshake over to
+// the game loop.
+func (w *World) queueLogin(p *Player) {
+	w.logins <- p
+}
+
+func (w *World) processLogins() {
+	for {
+		select {
+		case p := <-w.logins:
+			if old := p.replaces; old != nil {
+				// a reconnect carries on where the lost connection left off
+				p.applyRecord(old.Record())
+				w.disconnect(old)
+				p.replaces = nil
+			}
+			p.active = true
+			p.Login()
+		default:
+			return
+		}
+	}
+}
+
+func (w *World) processInbound(players []*Player) {
+	for _, p := range players {
+		if !p.active {
+			continue
+		}
+		if err := p.processQueuedPackets(); err != nil {
+			if err != ErrConnectionClosed {
+				p.logger(worldLog).Warn("packet handling failed", "error", err)
+			}
+			w.disconnect(p)
+		}
+	}
+}
+
+func (w *World) processLogic(players []*Player) {
+	for _, p := range players {
+		if !p.active {
+			continue
+		}
+		if p.TimeoutTimer.TimedOut() {
+			p.logger(netLog).Info("timed out")
+			w.disconnect(p)
+			continue
+		}
+		p.processMovement()
+		p.checkRegion()
+	}
+}
+
+func (w *World) updatePlayers(players []*Player) {
+	for _, p := range players {
+		if !p.active {
+			continue
+		}
+		p.Update()
+	}
+	for _, p := range players {
+		if p.active {
+			p.resetUpdateFlags()
+		}
+	}
+}
+
+func (w *World) flush(players []*Player) {
+	for _, p := range players {
+		if !p.active {
+			continue
+		}
+		if err := p.Flush(); err != nil {
+			p.logger(netLog).Warn("flush failed", "error", err)
+			w.disconnect(p)
+		}
+	}
+}
+
+func (w *World) disconnect(p *Player) {
+	if p.active {
+		w.SavePlayer(p)
+		p.logger(worldLog).Info("logged out")
+	}
+	p.Connected = false
+	p.active = false
+	p.Socket.Close()
+	w.Unregister(p)
+}
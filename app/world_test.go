@@ -0,0 +1,385 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"rs-go-server/config"
+	"rs-go-server/crypto"
+	rsio "rs-go-server/io"
+	"rs-go-server/logging"
+	"rs-go-server/repo"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestWorld creates a world with a fast cycle and plaintext logins, without
+// running it.
+func newTestWorld() *World {
+	logging.Configure(io.Discard, logging.FORMAT_TEXT, slog.LevelError)
+	cfg := config.Default()
+	cfg.Server.CycleMillis = 10
+	cfg.Server.TimeoutMillis = 500
+	cfg.Login.Encryption = false
+	cfg.Login.MaxConnectionsPerAddress = 100
+	return NewWorld(cfg)
+}
+
+// startTestWorld runs a world with a fast cycle and a listener feeding it,
+// both stopped when the test ends.  setup can change the world before it
+// starts.
+func startTestWorld(t *testing.T, setup ...func(w *World)) (*World, string) {
+	t.Helper()
+	w := newTestWorld()
+	for _, fn := range setup {
+		fn(w)
+	}
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go w.Run()
+	go func() {
+		for {
+			connection, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			go NewPlayer(connection).Listen(w)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		w.Stop()
+		<-w.Done()
+	})
+	return w, listener.Addr().String()
+}
+
+// testLogin performs the handshake of a plaintext login and returns the
+// response code along with the open connection.  It's called from several
+// goroutines at once, so it returns errors rather than failing the test.
+func testLogin(addr, username string, opcode byte) (LoginResponse, net.Conn, error) {
+	return testSecureLogin(addr, username, opcode, nil)
+}
+
+// testSecureLogin is testLogin with the secure block encrypted by key, the
+// public half of the world's key.
+func testSecureLogin(addr, username string, opcode byte, key *crypto.RSAKey) (LoginResponse, net.Conn, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	c.Write([]byte{14, 0})
+	if _, err := io.ReadFull(c, make([]byte, 17)); err != nil {
+		c.Close()
+		return 0, nil, err
+	}
+	c.Write(loginBlock(username, opcode, key))
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(c, response); err != nil {
+		c.Close()
+		return 0, nil, err
+	}
+	return LoginResponse(response[0]), c, nil
+}
+
+// loginBlock is the login block a client sends after the handshake, with
+// "password" as the password.  The secure part is encrypted when key is set.
+func loginBlock(username string, opcode byte, key *crypto.RSAKey) []byte {
+	var secure bytes.Buffer
+	secure.WriteByte(10)
+	binary.Write(&secure, binary.BigEndian, uint64(1))
+	binary.Write(&secure, binary.BigEndian, uint64(2))
+	binary.Write(&secure, binary.BigEndian, uint32(0))
+	secure.WriteString(username + "\n")
+	secure.WriteString("password\n")
+	secureBytes := secure.Bytes()
+	if key != nil {
+		// with the public exponent the same operation encrypts
+		secureBytes = key.Decrypt(secureBytes)
+	}
+
+	var block bytes.Buffer
+	block.WriteByte(255)
+	binary.Write(&block, binary.BigEndian, uint16(317))
+	block.WriteByte(0)
+	block.Write(make([]byte, 9*4))
+	block.WriteByte(byte(len(secureBytes)))
+	block.Write(secureBytes)
+	return append([]byte{opcode, byte(block.Len())}, block.Bytes()...)
+}
+
+// waitFor polls condition until it holds or a second has passed.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !condition(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Run with -race, logins and logouts race each other and the game loop.
+func TestConcurrentLoginLogout(t *testing.T) {
+	w, addr := startTestWorld(t)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	loggedIn := 0
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response, c, err := testLogin(addr, fmt.Sprintf("player%d", i), 16)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer c.Close()
+				switch response {
+				case LOGIN_OK:
+					mutex.Lock()
+					loggedIn++
+					mutex.Unlock()
+					time.Sleep(time.Duration(j*10) * time.Millisecond)
+				case LOGIN_ALREADY_LOGGED_IN:
+				default:
+					t.Errorf("unexpected login response %d", response)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	if loggedIn < 20 {
+		t.Errorf("%d logins succeeded, want at least one per username", loggedIn)
+	}
+	waitFor(t, "every player to be removed", func() bool { return w.PlayerCount() == 0 })
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	_, addr := startTestWorld(t)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("connection that never logged in was not closed: %v", err)
+	}
+}
+
+func TestRejectInvalidUsername(t *testing.T) {
+	_, addr := startTestWorld(t)
+	for _, username := range []string{"x/../bob", "thirteen char", "   "} {
+		response, c, err := testLogin(addr, username, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		if response != LOGIN_INVALID_CREDENTIALS {
+			t.Errorf("login as %q got response %d, want %d", username, response, LOGIN_INVALID_CREDENTIALS)
+		}
+	}
+}
+
+func TestDuplicateLoginBySpelling(t *testing.T) {
+	_, addr := startTestWorld(t)
+	response, first, err := testLogin(addr, "Bob The Cat", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if response != LOGIN_OK {
+		t.Fatalf("first login got response %d", response)
+	}
+	for _, username := range []string{"bob the cat", "BOB_THE_CAT", "bob the cat_"} {
+		response, c, err := testLogin(addr, username, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		if response != LOGIN_ALREADY_LOGGED_IN {
+			t.Errorf("login as %q got response %d, want %d", username, response, LOGIN_ALREADY_LOGGED_IN)
+		}
+	}
+}
+
+func TestReconnectTakesOver(t *testing.T) {
+	w, addr := startTestWorld(t)
+	response, first, err := testLogin(addr, "bob", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if response != LOGIN_OK {
+		t.Fatalf("first login got response %d", response)
+	}
+	response, second, err := testLogin(addr, "bob", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if response != LOGIN_RECONNECT_OK {
+		t.Fatalf("reconnect got response %d, want %d", response, LOGIN_RECONNECT_OK)
+	}
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(io.Discard, first); err != nil {
+		t.Fatalf("replaced connection was not closed: %v", err)
+	}
+	waitFor(t, "the replaced player to be removed", func() bool { return w.PlayerCount() == 1 })
+}
+
+func TestReconnectWithoutSession(t *testing.T) {
+	w, addr := startTestWorld(t)
+	response, c, err := testLogin(addr, "bob", 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if response != LOGIN_OK {
+		t.Fatalf("reconnect without a session got response %d, want %d", response, LOGIN_OK)
+	}
+	// a fresh login is followed by the rights and flagged bytes
+	frame := make([]byte, 2)
+	if _, err := io.ReadFull(c, frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != RIGHTS_PLAYER || frame[1] != 0 {
+		t.Errorf("login frame ended with % x", frame)
+	}
+	waitFor(t, "the player to be registered", func() bool { return w.PlayerCount() == 1 })
+}
+
+func TestRejectDisabledAccount(t *testing.T) {
+	accounts, err := repo.NewJSONAccountRepository(filepath.Join(t.TempDir(), "accounts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	accounts.CreateAccount(&repo.AccountRecord{Name: rsio.NameToLong("banned"), Username: "banned", PasswordHash: string(hash), Disabled: true})
+	_, addr := startTestWorld(t, func(w *World) { w.Accounts = accounts })
+
+	response, c, err := testLogin(addr, "banned", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if response != LOGIN_ACCOUNT_DISABLED {
+		t.Errorf("login to a disabled account got response %d, want %d", response, LOGIN_ACCOUNT_DISABLED)
+	}
+}
+
+func TestRejectedLoginCreatesNoAccount(t *testing.T) {
+	accounts, err := repo.NewJSONAccountRepository(filepath.Join(t.TempDir(), "accounts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, addr := startTestWorld(t, func(w *World) {
+		w.Accounts = accounts
+		w.LoginValidators = append(w.LoginValidators, func(w *World, request *LoginRequest) LoginResponse {
+			return LOGIN_MEMBERS_ONLY
+		})
+	})
+	response, c, err := testLogin(addr, "alice", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if response != LOGIN_MEMBERS_ONLY {
+		t.Fatalf("login got response %d, want %d", response, LOGIN_MEMBERS_ONLY)
+	}
+	if _, err := accounts.LoadAccount(rsio.NameToLong("alice")); err != repo.ErrAccountNotFound {
+		t.Errorf("rejected login registered an account: %v", err)
+	}
+}
+
+func TestPlaintextPasswordCleared(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	connection, err := listener.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlayer(connection)
+	defer p.Socket.Close()
+	p.world = newTestWorld()
+	p.TimeoutTimer = NewTimer(p.world.Config.Timeout())
+
+	client.Write([]byte{14, 0})
+	if err := p.HandleIncomingData(); err != nil {
+		t.Fatal(err)
+	}
+	client.Write(loginBlock("bob", 16, nil))
+	for p.LoginStage != LOGGED_IN {
+		if err := p.HandleIncomingData(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bytes.Contains(p.inBuffer.Buf, []byte("password")) {
+		t.Error("the password was left in the connection's buffer")
+	}
+	if bytes.Contains(p.readBuffer, []byte("password")) {
+		t.Error("the password was left in the socket read buffer")
+	}
+}
+
+func TestSecureLogin(t *testing.T) {
+	private, public, err := crypto.GenerateRSAKey(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, addr := startTestWorld(t, func(w *World) { w.RSAKey = private })
+	response, c, err := testSecureLogin(addr, "Bob", 16, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if response != LOGIN_OK {
+		t.Fatalf("login got response %d, want %d", response, LOGIN_OK)
+	}
+	if p := w.PlayerByUsername("bob"); p == nil || p.Username != "Bob" {
+		t.Errorf("the username wasn't read from the decrypted block")
+	}
+}
+
+func TestLoginBlockOpcode(t *testing.T) {
+	_, addr := startTestWorld(t)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	c.Write([]byte{14, 0})
+	if _, err := io.ReadFull(c, make([]byte, 17)); err != nil {
+		t.Fatal(err)
+	}
+	block := loginBlock("bob", 16, nil)
+	block[2+1+2+1+9*4+1] = 11 // the secure block's opcode
+	c.Write(block)
+	if n, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("a secure block without opcode 10 was accepted, read %d bytes: %v", n, err)
+	}
+}
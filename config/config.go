@@ -0,0 +1,302 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"rs-go-server/logging"
+	"strings"
+	"time"
+)
+
+// ENV_PREFIX is prepended to a flag's name, upper cased and with dashes
+// replaced by underscores, to get the environment variable overriding it.
+const ENV_PREFIX = "RS_"
+
+// MAX_PLAYERS is the most players the client can see in one world.  It
+// addresses players with an 11 bit index where 0 is unused and 2047 is
+// reserved as a marker.
+const MAX_PLAYERS = 2046
+
+type InvalidConfigError struct{ Field, Reason string }
+
+func (e InvalidConfigError) Error() string {
+	return fmt.Sprintf("config/config: invalid setting.  Field: %s, Reason: %s", e.Field, e.Reason)
+}
+
+// Config holds every setting of the server.  Settings come from Default,
+// then the config file, then environment variables and finally flags, each
+// overriding the last.
+type Config struct {
+	Server  ServerConfig `json:"server"`
+	Login   LoginConfig  `json:"login"`
+	Spawn   SpawnConfig  `json:"spawn"`
+	Starter []ItemConfig `json:"starter_items"`
+	Data    DataConfig   `json:"data"`
+	Logging LogConfig    `json:"logging"`
+}
+
+type ServerConfig struct {
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	MaxPlayers int    `json:"max_players"`
+	// CycleMillis is the length of a game tick.
+	CycleMillis int `json:"cycle_millis"`
+	// TimeoutMillis is how long a connection may go without sending anything
+	// before it's dropped.
+	TimeoutMillis int `json:"timeout_millis"`
+	// ShutdownTimeoutMillis bounds how long shutting down waits for saves
+	// to be written.
+	ShutdownTimeoutMillis int `json:"shutdown_timeout_millis"`
+}
+
+type LoginConfig struct {
+	// Encryption toggles ISAAC opcode encryption, disabling it is only useful
+	// for debugging with a patched client.
+	Encryption bool `json:"encryption"`
+	// RSAKey is the private key file for the login block, plaintext logins
+	// when empty.
+	RSAKey string `json:"rsa_key"`
+	// AutoRegister creates an account on the first login of an unknown
+	// username.
+	AutoRegister             bool `json:"auto_register"`
+	MaxConnectionsPerAddress int  `json:"max_connections_per_address"`
+}
+
+// SpawnConfig is where new characters start.
+type SpawnConfig struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+// ItemConfig is an item new characters start with.
+type ItemConfig struct {
+	ID     int `json:"id"`
+	Amount int `json:"amount"`
+}
+
+type DataConfig struct {
+	Items string `json:"items"`
+	// Cache is the directory holding obj.dat and obj.idx from the client
+	// cache, optional.
+	Cache string `json:"cache"`
+	// Storage is where characters are saved: json, sqlite or none.
+	Storage  string `json:"storage"`
+	Saves    string `json:"saves"`
+	Database string `json:"database"`
+	// Accounts is the account file passwords are checked against, any
+	// password is accepted when empty.
+	Accounts string `json:"accounts"`
+}
+
+type LogConfig struct {
+	// Level is the default level: debug, info, warn or error.
+	Level string `json:"level"`
+	// Format is text or json.
+	Format string `json:"format"`
+	// Subsystems overrides the level of single loggers by name, like net,
+	// login, world or packets.
+	Subsystems map[string]string `json:"subsystems"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Host:                  "0.0.0.0",
+			Port:                  43594,
+			MaxPlayers:            MAX_PLAYERS,
+			CycleMillis:           600,
+			TimeoutMillis:         5000,
+			ShutdownTimeoutMillis: 10000,
+		},
+		Login: LoginConfig{
+			Encryption:               true,
+			AutoRegister:             true,
+			MaxConnectionsPerAddress: 5,
+		},
+		Spawn: SpawnConfig{X: 3222, Y: 3218},
+		Starter: []ItemConfig{
+			{1038, 1}, {1040, 1}, {1042, 1}, {1044, 1}, {1046, 1}, {1048, 1},
+		},
+		Data: DataConfig{
+			Items:    "data/items.json",
+			Storage:  "json",
+			Saves:    "data/characters",
+			Database: "data/server.db",
+			Accounts: "data/accounts.json",
+		},
+		Logging: LogConfig{
+			Level:      "info",
+			Format:     logging.FORMAT_TEXT,
+			Subsystems: map[string]string{},
+		},
+	}
+}
+
+func (c *Config) CycleTime() time.Duration {
+	return time.Duration(c.Server.CycleMillis) * time.Millisecond
+}
+
+func (c *Config) Timeout() time.Duration {
+	return time.Duration(c.Server.TimeoutMillis) * time.Millisecond
+}
+
+func (c *Config) ShutdownTimeout() time.Duration {
+	return time.Duration(c.Server.ShutdownTimeoutMillis) * time.Millisecond
+}
+
+// bind registers a flag for every setting that can be overridden from the
+// command line, defaulting to its current value.
+func (c *Config) bind(flags *flag.FlagSet) {
+	flags.StringVar(&c.Server.Host, "host", c.Server.Host, "address to listen on")
+	flags.IntVar(&c.Server.Port, "port", c.Server.Port, "port to listen on")
+	flags.IntVar(&c.Server.MaxPlayers, "max-players", c.Server.MaxPlayers, "most players in the world at once")
+	flags.IntVar(&c.Server.CycleMillis, "cycle", c.Server.CycleMillis, "length of a game tick in milliseconds")
+	flags.IntVar(&c.Server.TimeoutMillis, "timeout", c.Server.TimeoutMillis, "milliseconds of silence before a connection is dropped")
+	flags.IntVar(&c.Server.ShutdownTimeoutMillis, "shutdown-timeout", c.Server.ShutdownTimeoutMillis, "milliseconds to wait for saves when shutting down")
+	flags.BoolVar(&c.Login.Encryption, "isaac", c.Login.Encryption, "encrypt packet opcodes with ISAAC, disable for debugging")
+	flags.StringVar(&c.Login.RSAKey, "rsa", c.Login.RSAKey, "private key file for the login block, plaintext logins when empty")
+	flags.BoolVar(&c.Login.AutoRegister, "register", c.Login.AutoRegister, "create an account on the first login of an unknown username")
+	flags.IntVar(&c.Login.MaxConnectionsPerAddress, "max-connections", c.Login.MaxConnectionsPerAddress, "most players logged in from one address")
+	flags.StringVar(&c.Logging.Level, "log-level", c.Logging.Level, "default log level: debug, info, warn or error")
+	flags.StringVar(&c.Logging.Format, "log-format", c.Logging.Format, "log output format: text or json")
+	flags.StringVar(&c.Data.Items, "items", c.Data.Items, "item definition data file")
+	flags.StringVar(&c.Data.Cache, "cache", c.Data.Cache, "directory holding obj.dat and obj.idx from the client cache, optional")
+	flags.StringVar(&c.Data.Storage, "storage", c.Data.Storage, "where characters are saved: json, sqlite or none")
+	flags.StringVar(&c.Data.Saves, "saves", c.Data.Saves, "directory of character files for json storage")
+	flags.StringVar(&c.Data.Database, "database", c.Data.Database, "database file for sqlite storage")
+	flags.StringVar(&c.Data.Accounts, "accounts", c.Data.Accounts, "account file passwords are checked against, any password is accepted when empty")
+}
+
+// Load builds the configuration from the file named by -config, environment
+// variables and the command line args.  A missing config file is only an
+// error when it was asked for explicitly.
+func Load(args []string) (*Config, error) {
+	// the first pass only finds the config file, the flags are parsed again
+	// once it's loaded so they override it
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := flags.String("config", "server.json", "config file, settings in it are overridden by environment variables and flags")
+	Default().bind(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := false
+	flags.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+	if env, ok := os.LookupEnv(envName("config")); ok && !explicit {
+		*path, explicit = env, true
+	}
+
+	c := Default()
+	if err := c.load(*path); err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+		return nil, err
+	}
+
+	flags = flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.String("config", *path, "")
+	c.bind(flags)
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if env, ok := os.LookupEnv(envName(f.Name)); ok && err == nil {
+			if setErr := f.Value.Set(env); setErr != nil {
+				err = InvalidConfigError{Field: envName(f.Name), Reason: setErr.Error()}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	return nil
+}
+
+func envName(flagName string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Validate reports every setting that is out of range.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, reason string) {
+		errs = append(errs, InvalidConfigError{Field: field, Reason: reason})
+	}
+	if net.ParseIP(c.Server.Host) == nil {
+		invalid("server.host", "must be an IP address")
+	}
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		invalid("server.port", "must be between 0 and 65535")
+	}
+	if c.Server.MaxPlayers < 1 || c.Server.MaxPlayers > MAX_PLAYERS {
+		invalid("server.max_players", fmt.Sprintf("must be between 1 and %d", MAX_PLAYERS))
+	}
+	if c.Server.CycleMillis <= 0 {
+		invalid("server.cycle_millis", "must be positive")
+	}
+	if c.Server.TimeoutMillis <= 0 {
+		invalid("server.timeout_millis", "must be positive")
+	}
+	if c.Server.ShutdownTimeoutMillis <= 0 {
+		invalid("server.shutdown_timeout_millis", "must be positive")
+	}
+	if c.Login.MaxConnectionsPerAddress < 1 {
+		invalid("login.max_connections_per_address", "must be at least 1")
+	}
+	if c.Spawn.X < 0 || c.Spawn.Y < 0 || c.Spawn.Z < 0 || c.Spawn.Z > 3 {
+		invalid("spawn", "must be a position in the map")
+	}
+	for i, item := range c.Starter {
+		if item.ID < 0 || item.Amount < 1 {
+			invalid(fmt.Sprintf("starter_items[%d]", i), "needs an id and a positive amount")
+		}
+	}
+	switch c.Data.Storage {
+	case "json":
+		if c.Data.Saves == "" {
+			invalid("data.saves", "is required for json storage")
+		}
+	case "sqlite":
+		if c.Data.Database == "" {
+			invalid("data.database", "is required for sqlite storage")
+		}
+	case "none":
+	default:
+		invalid("data.storage", "must be json, sqlite or none")
+	}
+	if c.Data.Items == "" {
+		invalid("data.items", "is required")
+	}
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		invalid("logging.level", "must be debug, info, warn or error")
+	}
+	for name, level := range c.Logging.Subsystems {
+		if _, err := logging.ParseLevel(level); err != nil {
+			invalid("logging.subsystems."+name, "must be debug, info, warn or error")
+		}
+	}
+	if c.Logging.Format != logging.FORMAT_TEXT && c.Logging.Format != logging.FORMAT_JSON {
+		invalid("logging.format", "must be text or json")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// writeConfig writes data to a config file in a temporary directory.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := `{"server": {"host": "127.0.0.1", "port": 1000}, "login": {"encryption": false}, "spawn": {"x": 3093, "y": 3493}}`
+	tests := []struct {
+		name     string
+		file     bool // whether -config names the file above
+		env      map[string]string
+		args     []string
+		expected func(c *Config)
+	}{
+		{"defaults", false, nil, nil, func(c *Config) {}},
+		{"file", true, nil, nil, func(c *Config) {
+			c.Server.Host, c.Server.Port, c.Login.Encryption = "127.0.0.1", 1000, false
+			c.Spawn = SpawnConfig{X: 3093, Y: 3493}
+		}},
+		{"environment over file", true, map[string]string{"RS_PORT": "2000", "RS_ISAAC": "true", "RS_LOG_LEVEL": "debug"}, nil, func(c *Config) {
+			c.Server.Host, c.Server.Port = "127.0.0.1", 2000
+			c.Spawn = SpawnConfig{X: 3093, Y: 3493}
+			c.Logging.Level = "debug"
+		}},
+		{"flags over environment", true, map[string]string{"RS_PORT": "2000", "RS_MAX_CONNECTIONS": "2"}, []string{"-port", "3000", "-isaac=false"}, func(c *Config) {
+			c.Server.Host, c.Server.Port, c.Login.Encryption = "127.0.0.1", 3000, false
+			c.Spawn = SpawnConfig{X: 3093, Y: 3493}
+			c.Login.MaxConnectionsPerAddress = 2
+		}},
+		{"environment without file", false, map[string]string{"RS_STORAGE": "sqlite"}, nil, func(c *Config) {
+			c.Data.Storage = "sqlite"
+		}},
+		{"flags without file", false, nil, []string{"-storage", "none", "-cycle", "300"}, func(c *Config) {
+			c.Data.Storage, c.Server.CycleMillis = "none", 300
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			if test.file {
+				args = append([]string{"-config", writeConfig(t, file)}, args...)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			c, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := Default()
+			test.expected(expected)
+			if !reflect.DeepEqual(c, expected) {
+				t.Errorf("loaded %+v, want %+v", c, expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigPath(t *testing.T) {
+	fromEnv := writeConfig(t, `{"server": {"port": 1000}}`)
+	fromFlag := writeConfig(t, `{"server": {"port": 2000}}`)
+	t.Setenv("RS_CONFIG", fromEnv)
+	if c, err := Load(nil); err != nil || c.Server.Port != 1000 {
+		t.Errorf("RS_CONFIG loaded port %v: %v", c, err)
+	}
+	if c, err := Load([]string{"-config", fromFlag}); err != nil || c.Server.Port != 2000 {
+		t.Errorf("-config over RS_CONFIG loaded %v: %v", c, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(err error) bool
+	}{
+		{"missing file from flag", nil, []string{"-config", missing}, func(err error) bool {
+			return errors.Is(err, os.ErrNotExist)
+		}},
+		{"missing file from environment", map[string]string{"RS_CONFIG": missing}, nil, func(err error) bool {
+			return errors.Is(err, os.ErrNotExist)
+		}},
+		{"unknown setting in file", nil, []string{"-config", writeConfig(t, `{"server": {"prot": 1}}`)}, func(err error) bool {
+			return err != nil
+		}},
+		{"malformed environment", map[string]string{"RS_PORT": "high"}, nil, func(err error) bool {
+			var invalid InvalidConfigError
+			return errors.As(err, &invalid) && invalid.Field == "RS_PORT"
+		}},
+		{"unknown flag", nil, []string{"-verbose"}, func(err error) bool {
+			return err != nil
+		}},
+		{"invalid after every layer", map[string]string{"RS_PORT": "1"}, []string{"-port", "70000"}, func(err error) bool {
+			return slices.Equal(invalidFields(err), []string{"server.port"})
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			if c, err := Load(test.args); c != nil || !test.check(err) {
+				t.Errorf("got %+v, %v", c, err)
+			}
+		})
+	}
+}
+
+// invalidFields lists the fields of the InvalidConfigErrors joined in err.
+func invalidFields(err error) []string {
+	var fields []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			fields = append(fields, invalidFields(err)...)
+		}
+	}
+	if invalid, ok := err.(InvalidConfigError); ok {
+		fields = append(fields, invalid.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(c *Config)
+		expected []string
+	}{
+		{"defaults", func(c *Config) {}, nil},
+		{"host", func(c *Config) { c.Server.Host = "localhost" }, []string{"server.host"}},
+		{"port", func(c *Config) { c.Server.Port = 65536 }, []string{"server.port"}},
+		{"no players", func(c *Config) { c.Server.MaxPlayers = 0 }, []string{"server.max_players"}},
+		{"too many players", func(c *Config) { c.Server.MaxPlayers = MAX_PLAYERS + 1 }, []string{"server.max_players"}},
+		{"cycle", func(c *Config) { c.Server.CycleMillis = 0 }, []string{"server.cycle_millis"}},
+		{"timeout", func(c *Config) { c.Server.TimeoutMillis = -1 }, []string{"server.timeout_millis"}},
+		{"shutdown timeout", func(c *Config) { c.Server.ShutdownTimeoutMillis = 0 }, []string{"server.shutdown_timeout_millis"}},
+		{"connections", func(c *Config) { c.Login.MaxConnectionsPerAddress = 0 }, []string{"login.max_connections_per_address"}},
+		{"spawn plane", func(c *Config) { c.Spawn.Z = 4 }, []string{"spawn"}},
+		{"starter amount", func(c *Config) { c.Starter[1].Amount = 0 }, []string{"starter_items[1]"}},
+		{"storage", func(c *Config) { c.Data.Storage = "mysql" }, []string{"data.storage"}},
+		{"json without saves", func(c *Config) { c.Data.Saves = "" }, []string{"data.saves"}},
+		{"sqlite without database", func(c *Config) { c.Data.Storage, c.Data.Database = "sqlite", "" }, []string{"data.database"}},
+		{"no storage needs no paths", func(c *Config) { c.Data.Storage, c.Data.Saves, c.Data.Database = "none", "", "" }, nil},
+		{"items", func(c *Config) { c.Data.Items = "" }, []string{"data.items"}},
+		{"log level", func(c *Config) { c.Logging.Level = "loud" }, []string{"logging.level"}},
+		{"subsystem level", func(c *Config) { c.Logging.Subsystems["net"] = "trace" }, []string{"logging.subsystems.net"}},
+		{"log format", func(c *Config) { c.Logging.Format = "xml" }, []string{"logging.format"}},
+		{"every problem at once", func(c *Config) {
+			c.Server.Port, c.Server.CycleMillis, c.Logging.Format = -1, 0, "xml"
+		}, []string{"server.port", "server.cycle_millis", "logging.format"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Default()
+			test.change(c)
+			err := c.Validate()
+			if fields := invalidFields(err); !slices.Equal(fields, test.expected) {
+				t.Errorf("invalid fields %v, want %v: %v", fields, test.expected, err)
+			}
+		})
+	}
+}
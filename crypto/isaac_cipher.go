@@ -4,6 +4,10 @@ import "rs-go-server/repo"
 
 const Size uint32 = 256
 
+// ENCRYPTOR_SEED_OFFSET is added to every word of the session seed for the
+// cipher of outgoing packets, the client does the same for its incoming ones.
+const ENCRYPTOR_SEED_OFFSET = 50
+
 type ISAACCipher struct {
 	a, b, c uint32
 	memory  [256]uint32
@@ -23,6 +27,16 @@ func NewISAACCipher(seed []uint32) repo.Cipher {
 	return z
 }
 
+// EncryptorSeed returns the seed of the outgoing cipher for a session whose
+// incoming cipher is seeded with seed.
+func EncryptorSeed(seed []uint32) []uint32 {
+	offset := make([]uint32, len(seed))
+	for i, s := range seed {
+		offset[i] = s + ENCRYPTOR_SEED_OFFSET
+	}
+	return offset
+}
+
 func (z *ISAACCipher) isaac() {
 	z.c++
 	z.b += z.c
@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+type InvalidRSAKeyError struct{ Path string }
+
+func (e InvalidRSAKeyError) Error() string {
+	return fmt.Sprintf("crypto/rsa: invalid key file.  Path: %s", e.Path)
+}
+
+// RSAKey is one half of the raw RSA key pair used by the client's login
+// block, without any padding scheme.
+type RSAKey struct {
+	Modulus  *big.Int
+	Exponent *big.Int
+}
+
+type rsaKeyFile struct {
+	Modulus  string `json:"modulus"`
+	Exponent string `json:"exponent"`
+}
+
+// GenerateRSAKey returns a new private and public key pair.
+func GenerateRSAKey(bits int) (private *RSAKey, public *RSAKey, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	private = &RSAKey{Modulus: key.N, Exponent: key.D}
+	public = &RSAKey{Modulus: key.N, Exponent: big.NewInt(int64(key.E))}
+	return private, public, nil
+}
+
+// LoadRSAKey reads a key written by Save, as the server's keygen subcommand
+// does, with the modulus and exponent stored as decimal strings.
+func LoadRSAKey(path string) (*RSAKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rsaKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	modulus, ok := new(big.Int).SetString(file.Modulus, 10)
+	if !ok || modulus.Sign() <= 0 {
+		return nil, InvalidRSAKeyError{Path: path}
+	}
+	exponent, ok := new(big.Int).SetString(file.Exponent, 10)
+	if !ok || exponent.Sign() <= 0 {
+		return nil, InvalidRSAKeyError{Path: path}
+	}
+	return &RSAKey{Modulus: modulus, Exponent: exponent}, nil
+}
+
+func (k *RSAKey) Save(path string) error {
+	data, err := json.MarshalIndent(rsaKeyFile{Modulus: k.Modulus.String(), Exponent: k.Exponent.String()}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Decrypt performs textbook RSA on block, as done by the client with
+// BigInteger.modPow.
+func (k *RSAKey) Decrypt(block []byte) []byte {
+	return new(big.Int).Exp(new(big.Int).SetBytes(block), k.Exponent, k.Modulus).Bytes()
+}
@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"bytes"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestRSAKeyRoundTrip(t *testing.T) {
+	private, public, err := GenerateRSAKey(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rsa.json")
+	if err := private.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRSAKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Modulus.Cmp(private.Modulus) != 0 || loaded.Exponent.Cmp(private.Exponent) != 0 {
+		t.Fatal("loaded key differs from the saved one")
+	}
+
+	// the client encrypts with the public half using the same operation
+	block := []byte{10, 0, 0, 0, 0, 0, 0, 0, 1, 'b', 'o', 'b', '\n'}
+	if decrypted := loaded.Decrypt(public.Decrypt(block)); !bytes.Equal(decrypted, block) {
+		t.Errorf("decrypted % x, want % x", decrypted, block)
+	}
+}
+
+func TestLoadRSAKeyInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rsa.json")
+	key := &RSAKey{Modulus: big.NewInt(0), Exponent: big.NewInt(3)}
+	if err := key.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRSAKey(path); err != (InvalidRSAKeyError{Path: path}) {
+		t.Errorf("loading a zero modulus got %v", err)
+	}
+}
@@ -94,7 +94,7 @@ func (bb *ByteBuffer) Flip() {
 func (bb *ByteBuffer) Compact() {
 	bb.Buf = bb.Buf[bb.Position:]
 	bb.maxWritten -= bb.Position
-	bb.Flip()
+	bb.Position = bb.maxWritten
 	bb.Resize(bb.initialSize)
 }
 
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"io"
 	"rs-go-server/repo"
-	"strings"
 )
 
 var ErrIllegalAccessType = errors.New("io/stream_buffer: illegal access type")
@@ -101,8 +100,9 @@ func (sb *StreamBuffer) WriteBytes(buf *ByteBuffer) {
 }
 
 func (sb *StreamBuffer) WriteBytesReverse(buf *ByteBuffer) {
-	for i := buf.Cap() - 1; i >= 0; i-- {
-		sb.WriteByte(int(buf.Buf[i]), STANDARD)
+	data := buf.Buffer()
+	for i := len(data) - 1; i >= 0; i-- {
+		sb.WriteByte(int(data[i]), STANDARD)
 	}
 }
 
@@ -144,6 +144,19 @@ func (sb *StreamBuffer) WriteBits(amount, value int) error {
 	return nil
 }
 
+// Reset empties the buffer so it can be written again.
+func (sb *StreamBuffer) Reset() {
+	sb.Buffer.Position = 0
+	sb.Buffer.maxWritten = 0
+	sb.bitPosition = 0
+}
+
+// BitPosition is the number of bits written so far, only meaningful in bit
+// access.
+func (sb *StreamBuffer) BitPosition() int {
+	return sb.bitPosition
+}
+
 func (sb *StreamBuffer) WriteBit(flag bool) {
 	bit := 0
 	if flag {
@@ -320,16 +333,22 @@ func (sb *StreamBuffer) ReadLong(valueType ValueType, order ByteOrder) uint64 {
 }
 
 func (sb *StreamBuffer) ReadString() string {
-	builder := strings.Builder{}
-	for {
+	return string(sb.ReadStringBytes())
+}
+
+// ReadStringBytes reads a newline terminated string as bytes, for secrets
+// that should be wiped once used rather than left behind in an immutable
+// string.
+func (sb *StreamBuffer) ReadStringBytes() []byte {
+	var data []byte
+	for sb.Remaining() > 0 {
 		tmp := sb.ReadByte(STANDARD)
 		if tmp == 10 {
 			break
-		} else {
-			builder.WriteByte(tmp)
 		}
+		data = append(data, tmp)
 	}
-	return builder.String()
+	return data
 }
 
 func (sb *StreamBuffer) ReadBytes(amount int, valueType ValueType) []byte {
@@ -356,5 +375,6 @@ func (sb *StreamBuffer) ReadBytesReverse(amount int, valueType ValueType) []byte
 		data[dataPosition] = val
 		dataPosition++
 	}
+	sb.Buffer.Position += amount
 	return data
 }
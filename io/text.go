@@ -0,0 +1,154 @@
+package io
+
+import "strings"
+
+// MaxTextLength is the longest chat message the client lets a player type.
+const MaxTextLength = 80
+
+// textCharacters is the client's chat character table, ordered by frequency
+// so the most common characters pack into a single nibble.
+var textCharacters = [...]rune{
+	' ', 'e', 't', 'a', 'o', 'i', 'h', 'n', 's', 'r', 'd', 'l', 'u', 'm',
+	'w', 'c', 'y', 'f', 'g', 'p', 'b', 'v', 'k', 'x', 'j', 'q', 'z', '0',
+	'1', '2', '3', '4', '5', '6', '7', '8', '9', ' ', '!', '?', '.', ',',
+	':', ';', '(', ')', '-', '&', '*', '\\', '\'', '@', '#', '+', '=', '£',
+	'$', '%', '"', '[', ']',
+}
+
+// UnpackText decodes chat text packed by the client.  Characters outside of
+// the table decode as spaces.
+func UnpackText(packed []byte) string {
+	builder := strings.Builder{}
+	high := -1
+	unpack := func(nibble int) {
+		switch {
+		case high != -1:
+			builder.WriteRune(textCharacter((high << 4) + nibble - 195))
+			high = -1
+		case nibble < 13:
+			builder.WriteRune(textCharacters[nibble])
+		default:
+			high = nibble
+		}
+	}
+	for _, b := range packed {
+		unpack(int(b>>4) & 0xF)
+		unpack(int(b) & 0xF)
+	}
+	return builder.String()
+}
+
+// PackText encodes text the way the client does, the 13 most common
+// characters take a nibble and the rest take two.  Text is lowercased first
+// and characters outside of the table pack as spaces.
+func PackText(text string) []byte {
+	packed := make([]byte, 0, len(text))
+	high := -1
+	for _, r := range strings.ToLower(text) {
+		index := textCharacterIndex(r)
+		if index > 12 {
+			index += 195
+		}
+		switch {
+		case high == -1 && index < 13:
+			high = index
+		case high == -1:
+			packed = append(packed, byte(index))
+		case index < 13:
+			packed = append(packed, byte(high<<4+index))
+			high = -1
+		default:
+			packed = append(packed, byte(high<<4+index>>4))
+			high = index & 0xF
+		}
+	}
+	if high != -1 {
+		packed = append(packed, byte(high<<4))
+	}
+	return packed
+}
+
+// FormatText capitalizes the first letter of every sentence, as the client
+// displays chat.
+func FormatText(text string) string {
+	builder := strings.Builder{}
+	capitalize := true
+	for _, r := range text {
+		if capitalize && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+			capitalize = false
+		}
+		if r == '.' || r == '!' || r == '?' {
+			capitalize = true
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// MaxNameLength is the longest username the client allows.
+const MaxNameLength = 12
+
+// NameToLong encodes a username in base 37 the way the client does, which
+// ignores case, treats anything but letters and digits as a space and drops
+// trailing spaces.  Two names with the same encoding are the same player.
+func NameToLong(name string) int64 {
+	var l int64
+	for i := 0; i < len(name) && i < MaxNameLength; i++ {
+		c := name[i]
+		l *= 37
+		switch {
+		case c >= 'A' && c <= 'Z':
+			l += int64(c-'A') + 1
+		case c >= 'a' && c <= 'z':
+			l += int64(c-'a') + 1
+		case c >= '0' && c <= '9':
+			l += int64(c-'0') + 27
+		}
+	}
+	for l%37 == 0 && l != 0 {
+		l /= 37
+	}
+	return l
+}
+
+// LongToName decodes a name encoded by NameToLong, in lower case with
+// underscores for spaces.
+func LongToName(l int64) string {
+	if l <= 0 {
+		return ""
+	}
+	name := make([]byte, 0, MaxNameLength)
+	for l != 0 {
+		c := l % 37
+		l /= 37
+		switch {
+		case c == 0:
+			name = append(name, '_')
+		case c < 27:
+			name = append(name, byte('a'+c-1))
+		default:
+			name = append(name, byte('0'+c-27))
+		}
+	}
+	for i, j := 0, len(name)-1; i < j; i, j = i+1, j-1 {
+		name[i], name[j] = name[j], name[i]
+	}
+	return string(name)
+}
+
+func textCharacter(index int) rune {
+	if index < 0 || index >= len(textCharacters) {
+		return ' '
+	}
+	return textCharacters[index]
+}
+
+func textCharacterIndex(r rune) int {
+	for i, c := range textCharacters {
+		if c == r {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package io
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPackText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		packed []byte
+		// the text read back, a trailing half byte unpacks as a space
+		unpacked string
+	}{
+		{"one nibble characters", "he", []byte{0x61}, "he"},
+		{"odd number of nibbles", "the", []byte{0x26, 0x10}, "the "},
+		{"two nibble character", "m", []byte{0xd0}, "m"},
+		{"two nibble character after one nibble", "ew", []byte{0x1d, 0x10}, "ew "},
+		{"one nibble character after two nibbles", "we", []byte{0xd1, 0x10}, "we "},
+		{"two nibble characters", "??", []byte{0xea, 0xea}, "??"},
+		{"upper case", "HE", []byte{0x61}, "he"},
+		{"unknown character", "a~a", []byte{0x30, 0x30}, "a a "},
+		{"non ascii character in the table", "£", []byte{0xfa}, "£"},
+		{"empty", "", []byte{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packed := PackText(test.text)
+			if !bytes.Equal(packed, test.packed) {
+				t.Errorf("PackText(%q) = % x, want % x", test.text, packed, test.packed)
+			}
+			if unpacked := UnpackText(packed); unpacked != test.unpacked {
+				t.Errorf("UnpackText(% x) = %q, want %q", packed, unpacked, test.unpacked)
+			}
+		})
+	}
+}
+
+func TestPackTextRoundTrip(t *testing.T) {
+	for _, r := range textCharacters {
+		text := string([]rune{'a', r, r, 'e'})
+		if unpacked := UnpackText(PackText(text)); unpacked != text && unpacked != text+" " {
+			t.Errorf("%q came back as %q", text, unpacked)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+
+	// SUBSYSTEM_KEY is the attribute naming the part of the server a record
+	// came from.
+	SUBSYSTEM_KEY = "subsystem"
+)
+
+type InvalidLevelError struct{ Level string }
+
+func (e InvalidLevelError) Error() string {
+	return fmt.Sprintf("logging/logging: invalid level.  Level: %s", e.Level)
+}
+
+type InvalidFormatError struct{ Format string }
+
+func (e InvalidFormatError) Error() string {
+	return fmt.Sprintf("logging/logging: invalid format.  Format: %s", e.Format)
+}
+
+var (
+	// output is the handler every logger writes through, swapped by
+	// Configure.  Loggers are created at init, before the configuration is
+	// known, so they can't hold on to the handler itself.
+	output atomic.Pointer[slog.Handler]
+
+	mutex      sync.Mutex
+	level      = new(slog.LevelVar)
+	subsystems = make(map[string]*subsystem)
+)
+
+type subsystem struct {
+	level *slog.LevelVar
+	// set when the level was configured for this subsystem rather than
+	// following the default level
+	overridden bool
+}
+
+func init() {
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+	output.Store(&h)
+}
+
+// For returns the logger of a subsystem, creating it on first use.
+func For(name string) *slog.Logger {
+	mutex.Lock()
+	defer mutex.Unlock()
+	s, ok := subsystems[name]
+	if !ok {
+		s = &subsystem{level: new(slog.LevelVar)}
+		s.level.Set(level.Level())
+		subsystems[name] = s
+	}
+	return slog.New(&handler{level: s.level}).With(SUBSYSTEM_KEY, name)
+}
+
+// Configure sets where and how records are written, and the default level
+// of every subsystem without a level of its own.
+func Configure(w io.Writer, format string, defaultLevel slog.Level) error {
+	var h slog.Handler
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	switch format {
+	case FORMAT_TEXT:
+		h = slog.NewTextHandler(w, options)
+	case FORMAT_JSON:
+		h = slog.NewJSONHandler(w, options)
+	default:
+		return InvalidFormatError{Format: format}
+	}
+	output.Store(&h)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	level.Set(defaultLevel)
+	for _, s := range subsystems {
+		if !s.overridden {
+			s.level.Set(defaultLevel)
+		}
+	}
+	return nil
+}
+
+// SetLevel changes the level of one subsystem, it can be called at any time.
+func SetLevel(name string, l slog.Level) {
+	For(name)
+	mutex.Lock()
+	defer mutex.Unlock()
+	subsystems[name].level.Set(l)
+	subsystems[name].overridden = true
+}
+
+// Subsystems returns the names of every subsystem logger created so far.
+func Subsystems() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	names := make([]string, 0, len(subsystems))
+	for name := range subsystems {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ParseLevel accepts debug, info, warn and error in any case.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil || strings.ContainsAny(s, "+-") {
+		return 0, InvalidLevelError{Level: s}
+	}
+	return l, nil
+}
+
+// handler filters records by its subsystem's level and replays the
+// attributes and groups added to the logger onto the current output.
+type handler struct {
+	level *slog.LevelVar
+	ops   []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	out := *output.Load()
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	return out.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &handler{level: h.level, ops: ops}
+}
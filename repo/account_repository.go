@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAccountNotFound = errors.New("repo/account_repository: account not found")
+	ErrAccountExists   = errors.New("repo/account_repository: account already exists")
+)
+
+// AccountRecord holds the credentials of a username.  Accounts are keyed by
+// the username's base 37 encoding, so names differing only in case or
+// spacing share an account.  A disabled account can't log in.
+type AccountRecord struct {
+	Name         int64     `json:"name"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"passwordHash"`
+	Created      time.Time `json:"created"`
+	Disabled     bool      `json:"disabled,omitempty"`
+}
+
+// AccountRepository stores accounts.  LoadAccount returns ErrAccountNotFound
+// for an unknown name and CreateAccount ErrAccountExists for a taken one.
+type AccountRepository interface {
+	LoadAccount(name int64) (*AccountRecord, error)
+	CreateAccount(account *AccountRecord) error
+}
+
+// JSONAccountRepository keeps every account in memory and in a single JSON
+// file, rewritten whenever an account is created.
+type JSONAccountRepository struct {
+	Path string
+
+	mutex    sync.Mutex
+	accounts map[int64]*AccountRecord
+}
+
+// NewJSONAccountRepository loads the accounts in path, which doesn't need to
+// exist yet.
+func NewJSONAccountRepository(path string) (*JSONAccountRepository, error) {
+	r := &JSONAccountRepository{Path: path, accounts: make(map[int64]*AccountRecord)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, os.MkdirAll(filepath.Dir(path), 0755)
+	} else if err != nil {
+		return nil, err
+	}
+	var accounts []*AccountRecord
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		r.accounts[account.Name] = account
+	}
+	return r, nil
+}
+
+func (r *JSONAccountRepository) LoadAccount(name int64) (*AccountRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	account, ok := r.accounts[name]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	loaded := *account
+	return &loaded, nil
+}
+
+func (r *JSONAccountRepository) CreateAccount(account *AccountRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.accounts[account.Name]; ok {
+		return ErrAccountExists
+	}
+	created := *account
+	r.accounts[account.Name] = &created
+	if err := r.save(); err != nil {
+		delete(r.accounts, account.Name)
+		return err
+	}
+	return nil
+}
+
+func (r *JSONAccountRepository) save() error {
+	accounts := make([]*AccountRecord, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	data, err := json.MarshalIndent(accounts, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.Path, data)
+}
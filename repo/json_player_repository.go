@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// JSONPlayerRepository keeps every character in its own JSON file.
+type JSONPlayerRepository struct {
+	Directory string
+}
+
+func NewJSONPlayerRepository(directory string) (*JSONPlayerRepository, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &JSONPlayerRepository{Directory: directory}, nil
+}
+
+func (r *JSONPlayerRepository) path(key string) (string, error) {
+	if !ValidPlayerKey(key) {
+		return "", InvalidPlayerKeyError{Key: key}
+	}
+	return filepath.Join(r.Directory, key+".json"), nil
+}
+
+func (r *JSONPlayerRepository) LoadPlayer(key string) (*PlayerRecord, error) {
+	path, err := r.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrPlayerNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return DecodePlayerRecord(data)
+}
+
+// SavePlayer replaces the character's file atomically.
+func (r *JSONPlayerRepository) SavePlayer(key string, record *PlayerRecord) error {
+	path, err := r.path(key)
+	if err != nil {
+		return err
+	}
+	data, err := EncodePlayerRecord(record)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PLAYER_RECORD_VERSION is the current version of the saved character format.
+// Bump it and add a migration whenever the format changes.
+const PLAYER_RECORD_VERSION = 1
+
+var ErrPlayerNotFound = errors.New("repo/player_repository: player not found")
+
+type UnsupportedPlayerVersionError struct{ Version int }
+
+func (e UnsupportedPlayerVersionError) Error() string {
+	return fmt.Sprintf("repo/player_repository: unsupported player record version.  Version: %d", e.Version)
+}
+
+type InvalidPlayerKeyError struct{ Key string }
+
+func (e InvalidPlayerKeyError) Error() string {
+	return fmt.Sprintf("repo/player_repository: invalid player key.  Key: %q", e.Key)
+}
+
+// PlayerRepository loads and saves characters under a key the caller derives
+// from the username, see ValidPlayerKey.  LoadPlayer returns
+// ErrPlayerNotFound for a character that has never been saved.
+type PlayerRepository interface {
+	LoadPlayer(key string) (*PlayerRecord, error)
+	SavePlayer(key string, record *PlayerRecord) error
+}
+
+// PlayerRecord is everything kept about a character between sessions.
+// Containers only list the slots that hold an item.
+type PlayerRecord struct {
+	Version    int              `json:"version"`
+	Username   string           `json:"username"`
+	Rights     int              `json:"rights"`
+	Position   PositionRecord   `json:"position"`
+	Appearance AppearanceRecord `json:"appearance"`
+	Skills     []SkillRecord    `json:"skills"`
+	Inventory  []ItemRecord     `json:"inventory"`
+	Equipment  []ItemRecord     `json:"equipment"`
+	Bank       []ItemRecord     `json:"bank"`
+	Settings   SettingsRecord   `json:"settings"`
+}
+
+type PositionRecord struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+type AppearanceRecord struct {
+	Gender int   `json:"gender"`
+	Kits   []int `json:"kits"`
+	Colors []int `json:"colors"`
+}
+
+type SkillRecord struct {
+	Level      int `json:"level"`
+	Experience int `json:"experience"`
+}
+
+type ItemRecord struct {
+	Slot   int `json:"slot"`
+	ID     int `json:"id"`
+	Amount int `json:"amount"`
+}
+
+type SettingsRecord struct {
+	BankNoteMode   bool `json:"bankNoteMode"`
+	BankInsertMode bool `json:"bankInsertMode"`
+}
+
+// playerRecordMigrations upgrade a decoded record from the version it's keyed
+// by to the next one.
+var playerRecordMigrations = map[int]func(record map[string]any) error{}
+
+// ValidPlayerKey reports whether key can name a character's save, it may
+// only hold lower case letters, digits and underscores so it can't escape
+// the save directory.
+func ValidPlayerKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodePlayerRecord serializes record as JSON at the current version.
+func EncodePlayerRecord(record *PlayerRecord) ([]byte, error) {
+	current := *record
+	current.Version = PLAYER_RECORD_VERSION
+	return json.MarshalIndent(&current, "", "\t")
+}
+
+// DecodePlayerRecord parses a record written by EncodePlayerRecord at any
+// earlier version, migrating it to the current one.
+func DecodePlayerRecord(data []byte) (*PlayerRecord, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	version, _ := raw["version"].(float64)
+	if int(version) < 1 || int(version) > PLAYER_RECORD_VERSION {
+		return nil, UnsupportedPlayerVersionError{Version: int(version)}
+	}
+	if int(version) < PLAYER_RECORD_VERSION {
+		for v := int(version); v < PLAYER_RECORD_VERSION; v++ {
+			migrate, ok := playerRecordMigrations[v]
+			if !ok {
+				return nil, UnsupportedPlayerVersionError{Version: v}
+			}
+			if err := migrate(raw); err != nil {
+				return nil, err
+			}
+		}
+		raw["version"] = PLAYER_RECORD_VERSION
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, err
+		}
+	}
+	var record PlayerRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
@@ -0,0 +1,351 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations build the schema one version at a time, the database's
+// user_version is the number of migrations applied.  Never edit a migration
+// that has shipped, append a new one instead.
+var sqliteMigrations = []string{
+	`CREATE TABLE players (
+		id               INTEGER PRIMARY KEY,
+		username         TEXT    NOT NULL UNIQUE,
+		display_name     TEXT    NOT NULL,
+		rights           INTEGER NOT NULL,
+		x                INTEGER NOT NULL,
+		y                INTEGER NOT NULL,
+		z                INTEGER NOT NULL,
+		gender           INTEGER NOT NULL,
+		kits             TEXT    NOT NULL,
+		colors           TEXT    NOT NULL,
+		bank_note_mode   INTEGER NOT NULL,
+		bank_insert_mode INTEGER NOT NULL,
+		total_experience INTEGER NOT NULL,
+		saved_at         TEXT    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE player_skills (
+		player_id  INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+		skill      INTEGER NOT NULL,
+		level      INTEGER NOT NULL,
+		experience INTEGER NOT NULL,
+		PRIMARY KEY (player_id, skill)
+	);
+	CREATE INDEX player_skills_experience ON player_skills (skill, experience DESC);
+	CREATE TABLE player_items (
+		player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+		container TEXT    NOT NULL,
+		slot      INTEGER NOT NULL,
+		item_id   INTEGER NOT NULL,
+		amount    INTEGER NOT NULL,
+		PRIMARY KEY (player_id, container, slot)
+	);
+	CREATE INDEX player_items_item ON player_items (item_id);`,
+}
+
+const (
+	CONTAINER_INVENTORY = "inventory"
+	CONTAINER_EQUIPMENT = "equipment"
+	CONTAINER_BANK      = "bank"
+)
+
+// HiscoreEntry is a row of the hiscores.  Levels are left to the caller to
+// work out from the experience, for the overall table it's the total.
+type HiscoreEntry struct {
+	Username   string
+	Experience int
+}
+
+// ItemHolding is an amount of an item owned by a player, for tracking down
+// duplicated items.
+type ItemHolding struct {
+	Username  string
+	Container string
+	Amount    int
+}
+
+// SQLitePlayerRepository keeps characters in an SQLite database, with skills
+// and items in their own tables so they can be queried.
+type SQLitePlayerRepository struct {
+	db *sql.DB
+}
+
+// NewSQLitePlayerRepository opens or creates the database at path and brings
+// its schema up to date.
+func NewSQLitePlayerRepository(path string) (*SQLitePlayerRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializes writers anyway, one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+	r := &SQLitePlayerRepository{db: db}
+	if err := r.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SQLitePlayerRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLitePlayerRepository) migrate() error {
+	var version int
+	if err := r.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return UnsupportedPlayerVersionError{Version: version}
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := r.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("repo/sqlite_player_repository: migration %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLitePlayerRepository) LoadPlayer(key string) (*PlayerRecord, error) {
+	if !ValidPlayerKey(key) {
+		return nil, InvalidPlayerKeyError{Key: key}
+	}
+	record := &PlayerRecord{Version: PLAYER_RECORD_VERSION}
+	var id int64
+	var kits, colors string
+	var noteMode, insertMode bool
+	err := r.db.QueryRow(`SELECT id, display_name, rights, x, y, z, gender, kits, colors, bank_note_mode, bank_insert_mode
+		FROM players WHERE username = ?`, key).Scan(
+		&id, &record.Username, &record.Rights, &record.Position.X, &record.Position.Y, &record.Position.Z,
+		&record.Appearance.Gender, &kits, &colors, &noteMode, &insertMode)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlayerNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	record.Settings = SettingsRecord{BankNoteMode: noteMode, BankInsertMode: insertMode}
+	if err := json.Unmarshal([]byte(kits), &record.Appearance.Kits); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(colors), &record.Appearance.Colors); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query("SELECT skill, level, experience FROM player_skills WHERE player_id = ? ORDER BY skill", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var skill int
+		var s SkillRecord
+		if err := rows.Scan(&skill, &s.Level, &s.Experience); err != nil {
+			return nil, err
+		}
+		for len(record.Skills) <= skill {
+			record.Skills = append(record.Skills, SkillRecord{Level: 1})
+		}
+		record.Skills[skill] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query("SELECT container, slot, item_id, amount FROM player_items WHERE player_id = ? ORDER BY container, slot", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var container string
+		var item ItemRecord
+		if err := rows.Scan(&container, &item.Slot, &item.ID, &item.Amount); err != nil {
+			return nil, err
+		}
+		switch container {
+		case CONTAINER_INVENTORY:
+			record.Inventory = append(record.Inventory, item)
+		case CONTAINER_EQUIPMENT:
+			record.Equipment = append(record.Equipment, item)
+		case CONTAINER_BANK:
+			record.Bank = append(record.Bank, item)
+		}
+	}
+	return record, rows.Err()
+}
+
+// SavePlayer replaces the character's rows in a single transaction, so a
+// failed save leaves the previous one intact.
+func (r *SQLitePlayerRepository) SavePlayer(key string, record *PlayerRecord) error {
+	if !ValidPlayerKey(key) {
+		return InvalidPlayerKeyError{Key: key}
+	}
+	kits, err := json.Marshal(record.Appearance.Kits)
+	if err != nil {
+		return err
+	}
+	colors, err := json.Marshal(record.Appearance.Colors)
+	if err != nil {
+		return err
+	}
+	totalExperience := 0
+	for _, skill := range record.Skills {
+		totalExperience += skill.Experience
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var id int64
+	err = tx.QueryRow(`INSERT INTO players (username, display_name, rights, x, y, z, gender, kits, colors,
+			bank_note_mode, bank_insert_mode, total_experience)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (username) DO UPDATE SET display_name = excluded.display_name, rights = excluded.rights,
+			x = excluded.x, y = excluded.y, z = excluded.z, gender = excluded.gender, kits = excluded.kits,
+			colors = excluded.colors, bank_note_mode = excluded.bank_note_mode,
+			bank_insert_mode = excluded.bank_insert_mode, total_experience = excluded.total_experience,
+			saved_at = CURRENT_TIMESTAMP
+		RETURNING id`,
+		key, record.Username, record.Rights,
+		record.Position.X, record.Position.Y, record.Position.Z, record.Appearance.Gender, string(kits), string(colors),
+		record.Settings.BankNoteMode, record.Settings.BankInsertMode, totalExperience).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM player_skills WHERE player_id = ?", id); err != nil {
+		return err
+	}
+	for skill, s := range record.Skills {
+		if _, err := tx.Exec("INSERT INTO player_skills (player_id, skill, level, experience) VALUES (?, ?, ?, ?)",
+			id, skill, s.Level, s.Experience); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM player_items WHERE player_id = ?", id); err != nil {
+		return err
+	}
+	insert, err := tx.Prepare("INSERT INTO player_items (player_id, container, slot, item_id, amount) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	containers := map[string][]ItemRecord{
+		CONTAINER_INVENTORY: record.Inventory,
+		CONTAINER_EQUIPMENT: record.Equipment,
+		CONTAINER_BANK:      record.Bank,
+	}
+	for container, items := range containers {
+		for _, item := range items {
+			if _, err := insert.Exec(id, container, item.Slot, item.ID, item.Amount); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Hiscores returns the top players in a skill by experience, or overall by
+// total experience when skill is -1.  Staff are left out.
+func (r *SQLitePlayerRepository) Hiscores(skill, limit int) ([]HiscoreEntry, error) {
+	var rows *sql.Rows
+	var err error
+	if skill == -1 {
+		rows, err = r.db.Query(`SELECT display_name, total_experience FROM players
+			WHERE rights = 0 ORDER BY total_experience DESC LIMIT ?`, limit)
+	} else {
+		rows, err = r.db.Query(`SELECT p.display_name, s.experience FROM player_skills s
+			JOIN players p ON p.id = s.player_id
+			WHERE s.skill = ? AND p.rights = 0 ORDER BY s.experience DESC LIMIT ?`, skill, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []HiscoreEntry
+	for rows.Next() {
+		var entry HiscoreEntry
+		if err := rows.Scan(&entry.Username, &entry.Experience); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ItemOwners lists every player holding an item, with the amounts per
+// container, largest first.
+func (r *SQLitePlayerRepository) ItemOwners(itemID int) ([]ItemHolding, error) {
+	rows, err := r.db.Query(`SELECT p.display_name, i.container, SUM(i.amount) AS amount FROM player_items i
+		JOIN players p ON p.id = i.player_id
+		WHERE i.item_id = ? GROUP BY p.id, i.container ORDER BY amount DESC`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var holdings []ItemHolding
+	for rows.Next() {
+		var holding ItemHolding
+		if err := rows.Scan(&holding.Username, &holding.Container, &holding.Amount); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, holding)
+	}
+	return holdings, rows.Err()
+}
+
+// FindPlayers returns the usernames whose key starts with prefix, for admins
+// looking up characters that may be offline.
+func (r *SQLitePlayerRepository) FindPlayers(prefix string, limit int) ([]string, error) {
+	rows, err := r.db.Query(`SELECT display_name FROM players WHERE username LIKE ? ESCAPE '\' ORDER BY username LIMIT ?`,
+		escapeLike(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, rows.Err()
+}
+
+func escapeLike(s string) string {
+	escaped := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' || s[i] == '_' || s[i] == '\\' {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, s[i])
+	}
+	return string(escaped)
+}
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func openTestDatabase(t *testing.T, path string) *SQLitePlayerRepository {
+	t.Helper()
+	r, err := NewSQLitePlayerRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func testRecord(username string, rights int, experience ...int) *PlayerRecord {
+	record := &PlayerRecord{
+		Version:    PLAYER_RECORD_VERSION,
+		Username:   username,
+		Rights:     rights,
+		Position:   PositionRecord{X: 3222, Y: 3218, Z: 1},
+		Appearance: AppearanceRecord{Gender: 1, Kits: []int{45, -1, 56, 61, 67, 70, 79}, Colors: []int{1, 2, 3, 4, 5}},
+		Inventory:  []ItemRecord{{Slot: 0, ID: 995, Amount: 1000}, {Slot: 27, ID: 385, Amount: 1}},
+		Equipment:  []ItemRecord{{Slot: 3, ID: 4151, Amount: 1}},
+		Bank:       []ItemRecord{{Slot: 0, ID: 386, Amount: 500}},
+		Settings:   SettingsRecord{BankNoteMode: true},
+	}
+	for _, xp := range experience {
+		record.Skills = append(record.Skills, SkillRecord{Level: 1, Experience: xp})
+	}
+	return record
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	r := openTestDatabase(t, filepath.Join(t.TempDir(), "server.db"))
+	record := testRecord("Bob The Cat", 1, 0, 500, 1154)
+	if err := r.SavePlayer("bob_the_cat", record); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := r.LoadPlayer("bob_the_cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, record) {
+		t.Errorf("loaded %+v, want %+v", loaded, record)
+	}
+
+	// a second save replaces the rows of the first
+	record.Inventory = record.Inventory[:1]
+	record.Bank = nil
+	record.Skills[1].Experience = 1000
+	if err := r.SavePlayer("bob_the_cat", record); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = r.LoadPlayer("bob_the_cat"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, record) {
+		t.Errorf("loaded %+v after saving again, want %+v", loaded, record)
+	}
+
+	if _, err := r.LoadPlayer("alice"); err != ErrPlayerNotFound {
+		t.Errorf("loading an unknown player got %v", err)
+	}
+	if _, err := r.LoadPlayer("x/../bob"); err != (InvalidPlayerKeyError{Key: "x/../bob"}) {
+		t.Errorf("loading an invalid key got %v", err)
+	}
+	if err := r.SavePlayer("Bob", record); err != (InvalidPlayerKeyError{Key: "Bob"}) {
+		t.Errorf("saving under an invalid key got %v", err)
+	}
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.db")
+	r := openTestDatabase(t, path)
+	if err := r.SavePlayer("bob", testRecord("Bob", 0)); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	migrations := sqliteMigrations
+	t.Cleanup(func() { sqliteMigrations = migrations })
+	sqliteMigrations = append(slices.Clip(migrations), `ALTER TABLE players ADD COLUMN title TEXT NOT NULL DEFAULT 'none'`)
+	r = openTestDatabase(t, path)
+	var version int
+	if err := r.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version is %d, want %d", version, len(sqliteMigrations))
+	}
+	var title string
+	if err := r.db.QueryRow("SELECT title FROM players WHERE username = 'bob'").Scan(&title); err != nil || title != "none" {
+		t.Errorf("new column holds %q: %v", title, err)
+	}
+	if _, err := r.LoadPlayer("bob"); err != nil {
+		t.Errorf("loading a player saved before the migration: %v", err)
+	}
+	r.Close()
+
+	// a database from a newer server is refused rather than used
+	sqliteMigrations = migrations
+	if _, err := NewSQLitePlayerRepository(path); err != (UnsupportedPlayerVersionError{Version: len(migrations) + 1}) {
+		t.Errorf("opening a newer database got %v", err)
+	}
+}
+
+func TestSQLiteFailedMigrationRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.db")
+	openTestDatabase(t, path).Close()
+
+	migrations := sqliteMigrations
+	t.Cleanup(func() { sqliteMigrations = migrations })
+	sqliteMigrations = append(slices.Clip(migrations), `CREATE TABLE titles (id INTEGER); SELECT * FROM missing`)
+	if _, err := NewSQLitePlayerRepository(path); err == nil {
+		t.Fatal("a failing migration was applied")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version, tables int
+	db.QueryRow("PRAGMA user_version").Scan(&version)
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'titles'").Scan(&tables)
+	if version != len(migrations) || tables != 0 {
+		t.Errorf("after a failed migration user_version is %d and %d titles tables exist", version, tables)
+	}
+}
+
+func TestSQLiteQueries(t *testing.T) {
+	r := openTestDatabase(t, filepath.Join(t.TempDir(), "server.db"))
+	players := map[string]*PlayerRecord{
+		"alice":   testRecord("Alice", 0, 100, 5000),
+		"bob":     testRecord("Bob", 0, 3000, 10),
+		"bobby":   testRecord("Bobby", 0, 10, 20),
+		"bobxcat": testRecord("Bobxcat", 0),
+		"bob_cat": testRecord("Bob cat", 0),
+		"mod":     testRecord("Mod", 1, 999999, 999999),
+	}
+	players["bobby"].Inventory = append(players["bobby"].Inventory, ItemRecord{Slot: 1, ID: 995, Amount: 50})
+	for key, record := range players {
+		if err := r.SavePlayer(key, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	overall, err := r.Hiscores(-1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []HiscoreEntry{{"Alice", 5100}, {"Bob", 3010}}; !slices.Equal(overall, expected) {
+		t.Errorf("overall hiscores %+v, want %+v", overall, expected)
+	}
+	second, err := r.Hiscores(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []HiscoreEntry{{"Alice", 5000}, {"Bobby", 20}, {"Bob", 10}}; !slices.Equal(second, expected) {
+		t.Errorf("hiscores of skill 1 %+v, want %+v", second, expected)
+	}
+
+	owners, err := r.ItemOwners(995)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != len(players) || owners[0] != (ItemHolding{"Bobby", CONTAINER_INVENTORY, 1050}) {
+		t.Errorf("owners of 995 %+v", owners)
+	}
+
+	found, err := r.FindPlayers("bob_", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"Bob cat"}; !slices.Equal(found, expected) {
+		t.Errorf("players starting with bob_ %v, want %v", found, expected)
+	}
+	found, err = r.FindPlayers("bob", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"Bob", "Bob cat"}; !slices.Equal(found, expected) {
+		t.Errorf("first two players starting with bob %v, want %v", found, expected)
+	}
+}
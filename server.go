@@ -1,94 +1,158 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"rs-go-server/app"
-	"time"
-)
-
-const (
-	Port        int = 43594
-	MaxPlayers      = 2000
-	CycleMillis     = 600
-)
-
-var (
-	Players = make([]*app.Player, MaxPlayers)
+	"rs-go-server/config"
+	"rs-go-server/crypto"
+	"rs-go-server/logging"
+	"rs-go-server/repo"
+	"syscall"
 )
 
 func main() {
-	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("0.0.0.0"), Port: Port})
-	if err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		keygen(os.Args[2:])
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := configureLogging(cfg.Logging); err != nil {
 		panic(err)
 	}
-	fmt.Printf("Listening on %v\n", listener.Addr())
-	go CheckPlayerTimeouts()
-	for {
-		connection, err := listener.AcceptTCP()
+	netLog := logging.For("net")
+	world := app.NewWorld(cfg)
+
+	if cfg.Data.Accounts != "" {
+		accounts, err := repo.NewJSONAccountRepository(cfg.Data.Accounts)
 		if err != nil {
-			fmt.Println(err)
-			continue
+			panic(err)
+		}
+		world.Accounts = accounts
+	}
+
+	switch cfg.Data.Storage {
+	case "json":
+		repository, err := repo.NewJSONPlayerRepository(cfg.Data.Saves)
+		if err != nil {
+			panic(err)
 		}
-		fmt.Println(connection.RemoteAddr())
-		if slot := NextPlayerSlot(); slot >= 0 {
-			Players[slot] = app.NewPlayer(slot, connection, func() {
-				Players[slot].Socket.Close()
-				Players[slot] = nil
-			})
-			go Players[slot].Cycle()
+		world.Repository = repository
+	case "sqlite":
+		repository, err := repo.NewSQLitePlayerRepository(cfg.Data.Database)
+		if err != nil {
+			panic(err)
 		}
+		world.Repository = repository
 	}
-}
 
-func CheckPlayerTimeouts() {
-	for {
-		cycleStart := time.Now()
-		for _, p := range Players {
-			if p == nil {
-				continue
-			}
-			if p.Connected && p.TimeoutTimer.TimedOut() {
-				fmt.Printf("Player %v has timed out, removing..\n", p.Username)
-				p.DisconnectFunc()
-			}
+	if cfg.Data.Cache != "" {
+		err := app.LoadItemDefinitionsFromCache(filepath.Join(cfg.Data.Cache, "obj.dat"), filepath.Join(cfg.Data.Cache, "obj.idx"))
+		if err != nil {
+			panic(err)
 		}
-		time.Sleep(time.Now().Sub(cycleStart) + CycleMillis*time.Millisecond)
 	}
-}
+	if err := app.LoadItemDefinitions(cfg.Data.Items); err != nil {
+		panic(err)
+	}
+	if err := app.ValidateItemDefinitions(); err != nil {
+		panic(err)
+	}
+	for i, item := range cfg.Starter {
+		if app.ItemDefinitionFor(item.ID) == nil {
+			panic(config.InvalidConfigError{Field: fmt.Sprintf("starter_items[%d]", i), Reason: "no such item"})
+		}
+	}
+	logging.For("world").Info("loaded item definitions", "count", app.ItemDefinitionCount())
 
-func UpdatePlayers() {
-	for {
-		cycleStart := time.Now()
-		for i, p := range Players {
-			if p == nil {
-				continue
-			}
-			if p.Connected {
-				err := p.Process()
-				if err != nil {
-					Players[i] = nil
-					continue
-				}
-			}
+	if cfg.Login.RSAKey != "" {
+		key, err := crypto.LoadRSAKey(cfg.Login.RSAKey)
+		if err != nil {
+			panic(err)
 		}
-		for _, p := range Players {
-			if p == nil {
-				continue
-			}
-			if p.Connected && p.LoginStage == app.LOGGED_IN {
-				p.Update()
-			}
+		world.RSAKey = key
+	}
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(cfg.Server.Host), Port: cfg.Server.Port})
+	if err != nil {
+		panic(err)
+	}
+	netLog.Info("listening", "address", listener.Addr().String())
+	go world.Run()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		world.Stop()
+		<-signals
+		netLog.Warn("interrupted again, exiting without waiting")
+		os.Exit(1)
+	}()
+	go func() {
+		<-world.Done()
+		listener.Close()
+	}()
+
+	for {
+		connection, err := listener.AcceptTCP()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			netLog.Warn("accept failed", "error", err)
+			continue
 		}
-		time.Sleep(time.Now().Sub(cycleStart) + CycleMillis*time.Millisecond)
+		netLog.Debug("accepted connection", "address", connection.RemoteAddr().String())
+		go app.NewPlayer(connection).Listen(world)
 	}
 }
 
-func NextPlayerSlot() int {
-	for i, p := range Players {
-		if p == nil || !p.Connected {
-			return i
+func configureLogging(cfg config.LogConfig) error {
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	if err := logging.Configure(os.Stderr, cfg.Format, level); err != nil {
+		return err
+	}
+	for name, s := range cfg.Subsystems {
+		level, err := logging.ParseLevel(s)
+		if err != nil {
+			return err
 		}
+		logging.SetLevel(name, level)
+	}
+	return nil
+}
+
+// keygen writes a new private key for -rsa and prints the public half to be
+// pasted into the client.
+func keygen(args []string) {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	bits := flags.Int("bits", 1024, "modulus size, the client's login block length limits this to 1024")
+	out := flags.String("out", "rsa.json", "private key output file")
+	flags.Parse(args)
+
+	private, public, err := crypto.GenerateRSAKey(*bits)
+	if err != nil {
+		panic(err)
+	}
+	if err := private.Save(*out); err != nil {
+		panic(err)
 	}
-	return -1
+	fmt.Printf("Private key written to %v\n", *out)
+	fmt.Printf("Client modulus:  %v\n", public.Modulus)
+	fmt.Printf("Client exponent: %v\n", public.Exponent)
 }